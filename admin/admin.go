@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"vokabelvision/pipeline"
+	"vokabelvision/scheduler"
+)
+
+//go:embed dashboard.html
+var dashboard []byte
+
+// Server exposes the scheduler and the run history over HTTP.
+type Server struct {
+	Scheduler *scheduler.Scheduler
+	RunsDir   string
+	// Token is required as "Authorization: Bearer <token>" on the POST
+	// endpoints. Empty leaves them open, which the config only allows when
+	// the server listens on a loopback address.
+	Token string
+}
+
+// scheduleStatus is the response of GET /api/schedule.
+type scheduleStatus struct {
+	Paused  bool              `json:"paused"`
+	Running bool              `json:"running"`
+	Entries []scheduler.Entry `json:"entries"`
+}
+
+// Handler returns the routes of the admin API and the dashboard.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleDashboard)
+	mux.HandleFunc("GET /api/schedule", s.handleSchedule)
+	mux.HandleFunc("POST /api/schedule/pause", s.authorize(s.handlePause))
+	mux.HandleFunc("POST /api/schedule/resume", s.authorize(s.handleResume))
+	mux.HandleFunc("POST /api/trigger", s.authorize(s.handleTrigger))
+	mux.HandleFunc("GET /api/runs", s.handleRuns)
+	mux.HandleFunc("GET /api/runs/{id}", s.handleRun)
+	mux.HandleFunc("GET /api/queue", s.handleQueue)
+	mux.HandleFunc("GET /runs/{id}/video", s.handleVideo)
+	return mux
+}
+
+// ListenAndServe serves the admin API on addr until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Admin server listening on %s", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authorize wraps a handler that changes state. It rejects requests sent by
+// pages of another origin, which a browser would send along with no
+// questions asked, and requests without the configured token.
+func (s *Server) authorize(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" {
+			u, err := url.Parse(origin)
+			if err != nil || u.Host != r.Host {
+				writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+				return
+			}
+		}
+		if s.Token != "" {
+			got := r.Header.Get("Authorization")
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+s.Token)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("missing or wrong admin token"))
+				return
+			}
+		}
+		h(w, r)
+	}
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboard)
+}
+
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) handlePause(w http.ResponseWriter, r *http.Request) {
+	s.Scheduler.Pause()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+func (s *Server) handleResume(w http.ResponseWriter, r *http.Request) {
+	s.Scheduler.Resume()
+	writeJSON(w, http.StatusOK, s.status())
+}
+
+// handleTrigger starts the job of the entry given by the "entry" query
+// parameter, or of the first scheduled entry if it is omitted.
+func (s *Server) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	entries := s.Scheduler.Entries()
+	if len(entries) == 0 {
+		writeError(w, http.StatusConflict, errors.New("nothing is scheduled"))
+		return
+	}
+	id := entries[0].ID
+	if q := r.URL.Query().Get("entry"); q != "" {
+		var err error
+		if id, err = strconv.Atoi(q); err != nil {
+			writeError(w, http.StatusBadRequest, errors.New("invalid entry"))
+			return
+		}
+	}
+	switch err := s.Scheduler.Trigger(id); {
+	case errors.Is(err, scheduler.ErrBusy):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, scheduler.ErrUnknownEntry):
+		writeError(w, http.StatusNotFound, err)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+	default:
+		writeJSON(w, http.StatusAccepted, s.status())
+	}
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	runs, err := pipeline.ListRuns(s.RunsDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// Newest first.
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+	}
+	writeJSON(w, http.StatusOK, nonNil(runs))
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	m, err := pipeline.LoadManifest(s.RunsDir, r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+func (s *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	pending, err := pipeline.Queue(s.RunsDir)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nonNil(pending))
+}
+
+func (s *Server) handleVideo(w http.ResponseWriter, r *http.Request) {
+	m, err := pipeline.LoadManifest(s.RunsDir, r.PathValue("id"))
+	if err != nil || m.VideoPath == "" {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, m.VideoPath)
+}
+
+func (s *Server) status() scheduleStatus {
+	return scheduleStatus{
+		Paused:  s.Scheduler.Paused(),
+		Running: s.Scheduler.Running(),
+		Entries: s.Scheduler.Entries(),
+	}
+}
+
+func nonNil(runs []*pipeline.Manifest) []*pipeline.Manifest {
+	if runs == nil {
+		return []*pipeline.Manifest{}
+	}
+	return runs
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,90 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors shared by every API client. Callers match them with errors.Is.
+var (
+	ErrRateLimited     = errors.New("rate limited")
+	ErrAuth            = errors.New("authentication failed")
+	ErrContentRejected = errors.New("content rejected")
+	ErrTimeout         = errors.New("timed out")
+	ErrUnavailable     = errors.New("service unavailable")
+	ErrBadResponse     = errors.New("unexpected response")
+)
+
+// APIError is returned when a provider answers with a non-success status.
+// It unwraps to the sentinel error that best describes the failure.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error: %v (status %d, body: %s)", e.Provider, e.Err, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// FromStatus builds an APIError for a response with the given status code and body.
+func FromStatus(provider string, statusCode int, body []byte) *APIError {
+	return &APIError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		Body:       string(body),
+		Err:        classify(statusCode, string(body)),
+	}
+}
+
+// classify maps an HTTP status code (and, for ambiguous codes, the body) to a sentinel error.
+func classify(statusCode int, body string) error {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case statusCode >= 500:
+		return ErrUnavailable
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		lower := strings.ToLower(body)
+		for _, hint := range []string{"moderation", "content policy", "content_policy", "safety", "nsfw", "inappropriate"} {
+			if strings.Contains(lower, hint) {
+				return ErrContentRejected
+			}
+		}
+	}
+	return ErrBadResponse
+}
+
+// Kind returns a short machine-readable name for the sentinel err wraps,
+// or "error" if it wraps none of them.
+func Kind(err error) string {
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrContentRejected):
+		return "content_rejected"
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrBadResponse):
+		return "bad_response"
+	}
+	return "error"
+}
@@ -0,0 +1,111 @@
+package backlog
+
+import (
+	"sync"
+	"time"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/jsonl"
+)
+
+// Entry is a vocab candidate that lost to a better one and is kept for a
+// later slot.
+type Entry struct {
+	AddedAt   time.Time         `json:"added_at"`
+	Candidate chatgpt.Candidate `json:"candidate"`
+	// Score is the candidate's score when it was generated.
+	Score float64 `json:"score"`
+}
+
+// Store is a JSON Lines file of unused vocab candidates.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns the backlog backed by the file at path. The file is created
+// on the first Add.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Add appends entries to the backlog.
+func (s *Store) Add(entries ...Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonl.Append(s.path, entries...)
+}
+
+// All returns every entry, oldest first.
+func (s *Store) All() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonl.Read[Entry](s.path)
+}
+
+// Take removes and returns the highest-scoring entry that use accepts, and
+// reports whether there was one. Entries for which drop reports true are
+// removed as well, such as words that were posted in the meantime.
+func (s *Store) Take(use, drop func(Entry) (bool, error)) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := jsonl.Read[Entry](s.path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	keep, best, err := pick(entries, use, drop)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	var taken Entry
+	if best >= 0 {
+		taken = keep[best]
+		keep = append(keep[:best], keep[best+1:]...)
+	}
+	if len(keep) != len(entries) {
+		if err := jsonl.Write(s.path, keep); err != nil {
+			return Entry{}, false, err
+		}
+	}
+	return taken, best >= 0, nil
+}
+
+// Peek returns the entry Take would return without changing the backlog.
+func (s *Store) Peek(use, drop func(Entry) (bool, error)) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := jsonl.Read[Entry](s.path)
+	if err != nil {
+		return Entry{}, false, err
+	}
+	keep, best, err := pick(entries, use, drop)
+	if err != nil || best < 0 {
+		return Entry{}, false, err
+	}
+	return keep[best], true, nil
+}
+
+// pick returns the entries drop does not reject and the index among them of
+// the highest-scoring one use accepts, or -1.
+func pick(entries []Entry, use, drop func(Entry) (bool, error)) ([]Entry, int, error) {
+	best := -1
+	var keep []Entry
+	for _, e := range entries {
+		d, err := drop(e)
+		if err != nil {
+			return nil, -1, err
+		}
+		if d {
+			continue
+		}
+		keep = append(keep, e)
+		ok, err := use(e)
+		if err != nil {
+			return nil, -1, err
+		}
+		if ok && (best < 0 || e.Score > keep[best].Score) {
+			best = len(keep) - 1
+		}
+	}
+	return keep, best, nil
+}
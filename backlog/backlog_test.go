@@ -0,0 +1,92 @@
+package backlog
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"vokabelvision/chatgpt"
+)
+
+func entry(word string, score float64) Entry {
+	return Entry{Candidate: chatgpt.Candidate{Vocab: chatgpt.Vocab{Word: word}}, Score: score}
+}
+
+func words(entries []Entry) []string {
+	var w []string
+	for _, e := range entries {
+		w = append(w, e.Candidate.Vocab.Word)
+	}
+	return w
+}
+
+func TestTake(t *testing.T) {
+	all := func(Entry) (bool, error) { return true, nil }
+	none := func(Entry) (bool, error) { return false, nil }
+	is := func(word string) func(Entry) (bool, error) {
+		return func(e Entry) (bool, error) { return e.Candidate.Vocab.Word == word, nil }
+	}
+	tests := []struct {
+		name      string
+		entries   []Entry
+		use, drop func(Entry) (bool, error)
+		// taken is the word of the taken entry, or empty if none.
+		taken string
+		left  []string
+	}{
+		{"empty backlog", nil, all, none, "", nil},
+		{"highest score", []Entry{entry("a", 0.5), entry("b", 0.9), entry("c", 0.7)}, all, none, "b", []string{"a", "c"}},
+		{"first of equal scores", []Entry{entry("a", 0.5), entry("b", 0.5)}, all, none, "a", []string{"b"}},
+		{"only accepted entries", []Entry{entry("a", 0.5), entry("b", 0.9)}, is("a"), none, "a", []string{"b"}},
+		{"nothing accepted", []Entry{entry("a", 0.5)}, none, none, "", []string{"a"}},
+		{"dropped entries are removed", []Entry{entry("a", 0.5), entry("b", 0.9)}, all, is("b"), "a", nil},
+		{"dropped even when nothing is taken", []Entry{entry("a", 0.5), entry("b", 0.9)}, none, is("b"), "", []string{"a"}},
+	}
+	for _, tt := range tests {
+		s := Open(filepath.Join(t.TempDir(), "backlog.jsonl"))
+		if len(tt.entries) > 0 {
+			if err := s.Add(tt.entries...); err != nil {
+				t.Fatal(err)
+			}
+		}
+		peeked, peekOK, err := s.Peek(tt.use, tt.drop)
+		if err != nil {
+			t.Fatalf("%s: Peek: %v", tt.name, err)
+		}
+		e, ok, err := s.Take(tt.use, tt.drop)
+		if err != nil {
+			t.Fatalf("%s: Take: %v", tt.name, err)
+		}
+		if ok != (tt.taken != "") || e.Candidate.Vocab.Word != tt.taken {
+			t.Errorf("%s: took %q (%v), want %q", tt.name, e.Candidate.Vocab.Word, ok, tt.taken)
+		}
+		if peekOK != ok || peeked.Candidate.Vocab.Word != e.Candidate.Vocab.Word {
+			t.Errorf("%s: peeked %q, took %q", tt.name, peeked.Candidate.Vocab.Word, e.Candidate.Vocab.Word)
+		}
+		left, err := s.All()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := words(left); !slices.Equal(got, tt.left) {
+			t.Errorf("%s: left %v, want %v", tt.name, got, tt.left)
+		}
+	}
+}
+
+func TestPeekLeavesBacklog(t *testing.T) {
+	s := Open(filepath.Join(t.TempDir(), "backlog.jsonl"))
+	if err := s.Add(entry("a", 0.5), entry("b", 0.9)); err != nil {
+		t.Fatal(err)
+	}
+	drop := func(e Entry) (bool, error) { return e.Candidate.Vocab.Word == "a", nil }
+	if _, _, err := s.Peek(func(Entry) (bool, error) { return true, nil }, drop); err != nil {
+		t.Fatal(err)
+	}
+	left, err := s.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := words(left); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Peek changed the backlog to %v", got)
+	}
+}
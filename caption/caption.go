@@ -0,0 +1,207 @@
+package caption
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/language"
+)
+
+// Instagram's limits for a reel caption.
+const (
+	DefaultMaxHashtags = 30
+	DefaultMaxLength   = 2200
+)
+
+//go:embed caption.tmpl
+var defaultTemplate string
+
+// generalHashtags is the pool used for every caption, whatever its level,
+// theme or language.
+var generalHashtags = []string{"#languagelearning", "#learnlanguages", "#vocabulary", "#wordoftheday", "#polyglot", "#studygram"}
+
+// Pool is a set of hashtags used for captions that match its level, theme
+// and language. Empty fields match everything.
+type Pool struct {
+	Level    string
+	Theme    string
+	Language string
+	Hashtags []string
+}
+
+// matches reports whether the pool applies to v.
+func (p Pool) matches(v chatgpt.Vocab, lang string) bool {
+	return (p.Level == "" || strings.EqualFold(p.Level, v.Request.Level)) &&
+		(p.Theme == "" || strings.EqualFold(p.Theme, v.Request.Topic)) &&
+		(p.Language == "" || strings.EqualFold(p.Language, lang))
+}
+
+// Options configures a Builder. Zero fields use the defaults.
+type Options struct {
+	// TemplateFile is a text/template file for the caption. Empty uses the
+	// built-in template.
+	TemplateFile string
+	// Pools are added to the built-in general and per-language pools.
+	Pools []Pool
+	// MaxHashtags defaults to DefaultMaxHashtags.
+	MaxHashtags int
+	// MaxLength is the longest caption in characters. Defaults to DefaultMaxLength.
+	MaxLength int
+	// CallToAction is an optional line such as "Save this for later!".
+	CallToAction string
+}
+
+// Data is what the caption template is executed with.
+type Data struct {
+	Vocab chatgpt.Vocab
+	// Text is the model's caption with its hashtags removed.
+	Text string
+	// Grammar is the grammar summary from GrammarLine.
+	Grammar      string
+	CallToAction string
+	// Hashtags are the chosen hashtags separated by spaces.
+	Hashtags string
+}
+
+// Builder renders captions.
+type Builder struct {
+	tmpl *template.Template
+	opts Options
+}
+
+// New parses the caption template and checks it against a sample vocab.
+func New(opts Options) (*Builder, error) {
+	text := defaultTemplate
+	if opts.TemplateFile != "" {
+		data, err := os.ReadFile(opts.TemplateFile)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("caption").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing caption template: %v", err)
+	}
+	if opts.MaxHashtags <= 0 {
+		opts.MaxHashtags = DefaultMaxHashtags
+	}
+	if opts.MaxLength <= 0 {
+		opts.MaxLength = DefaultMaxLength
+	}
+	for i, p := range opts.Pools {
+		if len(p.Hashtags) == 0 {
+			return nil, fmt.Errorf("hashtag pool %d is empty", i)
+		}
+	}
+	b := &Builder{tmpl: tmpl, opts: opts}
+	if _, err := b.Build(chatgpt.Vocab{Word: "der Apfel", Translation: "apple", Caption: "der Apfel = apple #deutsch", Sentence: "Der Apfel ist rot."}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// hashtagPattern matches a hashtag in a caption.
+var hashtagPattern = regexp.MustCompile(`#[\p{L}\p{N}_]+`)
+
+// Build renders the caption for v. Hashtags at the end of the lines of
+// v.Caption are moved to the hashtag line, followed by a random selection
+// from the pools that match v. Hashtags are not repeated, including those
+// the model used inside its text, and are limited to MaxHashtags in total.
+// If the caption is too long, hashtags are dropped from the end, and as a
+// last resort the text is cut.
+func (b *Builder) Build(v chatgpt.Vocab) (string, error) {
+	text, tags := splitHashtags(v.Caption)
+	inline := dedupe(hashtagPattern.FindAllString(text, -1))
+	tags = dedupe(append(append(inline, tags...), b.poolHashtags(v)...))[len(inline):]
+	limit := b.opts.MaxHashtags - len(inline)
+	if limit < 0 {
+		limit = 0
+	}
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+	data := Data{
+		Vocab:        v,
+		Text:         text,
+		Grammar:      GrammarLine(v),
+		CallToAction: b.opts.CallToAction,
+	}
+	for {
+		data.Hashtags = strings.Join(tags, " ")
+		var buf bytes.Buffer
+		if err := b.tmpl.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("error rendering caption template: %v", err)
+		}
+		caption := strings.TrimSpace(buf.String())
+		if n := len([]rune(caption)); n <= b.opts.MaxLength {
+			return caption, nil
+		} else if len(tags) == 0 {
+			return string([]rune(caption)[:b.opts.MaxLength-1]) + "…", nil
+		}
+		tags = tags[:len(tags)-1]
+	}
+}
+
+// poolHashtags returns the hashtags of every pool that matches v, shuffled
+// so each caption gets a different selection.
+func (b *Builder) poolHashtags(v chatgpt.Vocab) []string {
+	lang := language.LookupOr(v.Request.Language, language.DefaultTarget)
+	pools := append([]Pool{
+		{Hashtags: generalHashtags},
+		{Language: lang.Code, Hashtags: lang.Hashtags},
+	}, b.opts.Pools...)
+	var tags []string
+	for _, p := range pools {
+		if p.matches(v, lang.Code) {
+			for _, t := range p.Hashtags {
+				if !strings.HasPrefix(t, "#") {
+					t = "#" + t
+				}
+				tags = append(tags, t)
+			}
+		}
+	}
+	rand.Shuffle(len(tags), func(i, j int) { tags[i], tags[j] = tags[j], tags[i] })
+	return tags
+}
+
+// splitHashtags removes the hashtags at the end of each line of caption,
+// and lines made only of hashtags, and returns the remaining text and the
+// removed hashtags in order.
+func splitHashtags(caption string) (string, []string) {
+	var lines, tags []string
+	for _, line := range strings.Split(caption, "\n") {
+		words := strings.Fields(line)
+		end := len(words)
+		for end > 0 && hashtagPattern.FindString(words[end-1]) == words[end-1] {
+			end--
+		}
+		tags = append(tags, words[end:]...)
+		if end > 0 {
+			lines = append(lines, strings.Join(words[:end], " "))
+		}
+	}
+	return strings.Join(lines, "\n"), tags
+}
+
+// dedupe drops repeated hashtags, ignoring case, keeping the first occurrence.
+func dedupe(tags []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	for _, t := range tags {
+		key := strings.ToLower(t)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package caption
+
+import (
+	"strings"
+	"testing"
+
+	"vokabelvision/chatgpt"
+)
+
+func TestBuild(t *testing.T) {
+	apple := chatgpt.Vocab{
+		Word:                "der Apfel",
+		Translation:         "apple",
+		Caption:             "Heute lernen wir der Apfel #deutsch!\n#deutsch #Vokabel #lernen",
+		Sentence:            "Der Apfel ist rot.",
+		SentenceTranslation: "The apple is red.",
+		PartOfSpeech:        "noun",
+		Gender:              "masculine",
+		Plural:              "die Äpfel",
+		IPA:                 "/ˈapfl̩/",
+	}
+	tests := []struct {
+		name  string
+		opts  Options
+		vocab chatgpt.Vocab
+		// contains and excludes are substrings the caption must and must not have.
+		contains []string
+		excludes []string
+		// hashtags is the exact number of hashtags, if not negative.
+		hashtags  int
+		maxLength int
+	}{
+		{
+			name:     "grammar, pronunciation and example",
+			opts:     Options{MaxHashtags: 3},
+			vocab:    apple,
+			contains: []string{"Heute lernen wir der Apfel #deutsch!", "der Apfel (masculine) · Plural: die Äpfel", "Pronunciation: /ˈapfl̩/", "Example: Der Apfel ist rot. (The apple is red.)"},
+			hashtags: 3,
+		},
+		{
+			name:     "trailing hashtags are moved and not repeated",
+			opts:     Options{MaxHashtags: 30},
+			vocab:    apple,
+			contains: []string{"#Vokabel", "#lernen"},
+			excludes: []string{"#lernen\n"},
+			hashtags: -1,
+		},
+		{
+			name:     "call to action",
+			opts:     Options{MaxHashtags: 1, CallToAction: "Save this for later!"},
+			vocab:    apple,
+			contains: []string{"\n\nSave this for later!"},
+			hashtags: 1,
+		},
+		{
+			name:     "inline hashtags count towards the limit",
+			opts:     Options{MaxHashtags: 1},
+			vocab:    apple,
+			hashtags: 1,
+		},
+		{
+			name:     "matching pool",
+			opts:     Options{MaxHashtags: 30, Pools: []Pool{{Theme: "food", Hashtags: []string{"essen"}}, {Theme: "travel", Hashtags: []string{"#reisen"}}}},
+			vocab:    func() chatgpt.Vocab { v := apple; v.Request.Topic = "Food"; return v }(),
+			contains: []string{"#essen"},
+			excludes: []string{"#reisen"},
+			hashtags: -1,
+		},
+		{
+			name:      "hashtags are dropped to fit",
+			opts:      Options{MaxLength: 190},
+			vocab:     apple,
+			excludes:  []string{"…"},
+			hashtags:  -1,
+			maxLength: 190,
+		},
+		{
+			name:      "text is cut as a last resort",
+			opts:      Options{MaxLength: 40, MaxHashtags: 1},
+			vocab:     apple,
+			contains:  []string{"…"},
+			hashtags:  -1,
+			maxLength: 40,
+		},
+	}
+	for _, tt := range tests {
+		b, err := New(tt.opts)
+		if err != nil {
+			t.Fatalf("%s: New: %v", tt.name, err)
+		}
+		got, err := b.Build(tt.vocab)
+		if err != nil {
+			t.Fatalf("%s: Build: %v", tt.name, err)
+		}
+		for _, s := range tt.contains {
+			if !strings.Contains(got, s) {
+				t.Errorf("%s: caption does not contain %q:\n%s", tt.name, s, got)
+			}
+		}
+		for _, s := range tt.excludes {
+			if strings.Contains(got, s) {
+				t.Errorf("%s: caption contains %q:\n%s", tt.name, s, got)
+			}
+		}
+		tags := hashtagPattern.FindAllString(got, -1)
+		if tt.hashtags >= 0 && len(tags) != tt.hashtags {
+			t.Errorf("%s: got %d hashtags %v, want %d", tt.name, len(tags), tags, tt.hashtags)
+		}
+		if len(dedupe(tags)) != len(tags) {
+			t.Errorf("%s: repeated hashtags %v", tt.name, tags)
+		}
+		if tt.maxLength > 0 && len([]rune(got)) > tt.maxLength {
+			t.Errorf("%s: caption has %d characters, more than %d", tt.name, len([]rune(got)), tt.maxLength)
+		}
+	}
+}
+
+func TestNewRejectsEmptyPool(t *testing.T) {
+	if _, err := New(Options{Pools: []Pool{{Theme: "food"}}}); err == nil {
+		t.Error("New accepted an empty hashtag pool")
+	}
+}
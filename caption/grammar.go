@@ -0,0 +1,28 @@
+package caption
+
+import (
+	"strings"
+
+	"vokabelvision/chatgpt"
+)
+
+// GrammarLine summarizes the grammar of a vocab in one line: gender and
+// plural for nouns, principal parts for verbs. It is empty for other words.
+func GrammarLine(v chatgpt.Vocab) string {
+	switch strings.ToLower(v.PartOfSpeech) {
+	case "noun":
+		line := v.Word
+		if v.Gender != "" {
+			line += " (" + v.Gender + ")"
+		}
+		if v.Plural != "" && v.Plural != "-" {
+			line += " · Plural: " + v.Plural
+		}
+		return line
+	case "verb":
+		if v.Praeteritum != "" && v.PartizipII != "" {
+			return strings.Join([]string{v.Word, v.Praeteritum, v.PartizipII}, " · ")
+		}
+	}
+	return ""
+}
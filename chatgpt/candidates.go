@@ -0,0 +1,94 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/llm"
+)
+
+// Candidate is one of several vocabs offered by GetCandidates, with the
+// model's own estimates that help pick the best one.
+type Candidate struct {
+	Vocab Vocab `json:"vocab"`
+	// Level is the model's estimate of the word's CEFR level.
+	Level string `json:"level"`
+	// Drawability rates how easy the word is to show in a picture, from 1
+	// (abstract) to 5 (a concrete object).
+	Drawability int `json:"drawability"`
+}
+
+// candidatesSchema is the JSON schema of a GetCandidates answer.
+var candidatesSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"candidates": map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"vocab":       vocabSchema,
+					"level":       map[string]string{"type": "string"},
+					"drawability": map[string]string{"type": "integer"},
+				},
+				"required":             []string{"vocab", "level", "drawability"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"candidates"},
+	"additionalProperties": false,
+}
+
+// GetCandidates is like GetVocab but asks for n different vocabs in one
+// call. Invalid candidates are dropped; only if none is valid are the
+// problems sent back to the model, up to maxAttempts times.
+func GetCandidates(ctx context.Context, client llm.Client, req VocabRequest, prompt string, n int) ([]Candidate, error) {
+	prompt += fmt.Sprintf("\nGive %d different words as the 'candidates' array. Each candidate has the entry described above as 'vocab', "+
+		"your estimate of the word's CEFR level (A1 to C2) as 'level', and how easy the word is to show in a picture "+
+		"as 'drawability', from 1 (abstract) to 5 (a concrete object).", n)
+	messages := []llm.Message{{Role: "user", Content: prompt}}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		content, err := client.Complete(ctx, messages, &llm.Schema{Name: "candidates", Schema: candidatesSchema})
+		if err != nil {
+			return nil, err
+		}
+
+		var answer struct {
+			Candidates []Candidate `json:"candidates"`
+		}
+		if err := json.Unmarshal([]byte(llm.StripCodeFence(content)), &answer); err != nil {
+			lastErr = fmt.Errorf("%w: error parsing candidates JSON: %v", apierror.ErrBadResponse, err)
+			messages = append(messages,
+				llm.Message{Role: "assistant", Content: content},
+				llm.Message{Role: "user", Content: "That was not valid JSON. Return only the JSON object with the 'candidates' array."},
+			)
+			continue
+		}
+		var valid []Candidate
+		var problems []string
+		for i, c := range answer.Candidates {
+			c.Vocab.Request = req
+			c.Level = strings.ToUpper(strings.TrimSpace(c.Level))
+			if err := c.Vocab.Validate(); err != nil {
+				fmt.Printf("Candidate %d (%s) is invalid: %v\n", i+1, c.Vocab.Word, err)
+				problems = append(problems, fmt.Sprintf("candidate %d: %s", i+1, strings.Join(err.(*ValidationError).Problems, "; ")))
+				continue
+			}
+			valid = append(valid, c)
+		}
+		if len(valid) > 0 {
+			return valid, nil
+		}
+		lastErr = fmt.Errorf("%w: no valid candidate", ErrInvalidVocab)
+		messages = append(messages,
+			llm.Message{Role: "assistant", Content: content},
+			llm.Message{Role: "user", Content: "Your answer has these problems: " + strings.Join(problems, " | ") + ". Return corrected candidates."},
+		)
+	}
+	return nil, fmt.Errorf("no valid candidates after %d attempts: %w", maxAttempts, lastErr)
+}
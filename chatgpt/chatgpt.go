@@ -1,140 +1,275 @@
 package chatgpt
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"os"
 	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/language"
+	"vokabelvision/llm"
 )
 
-// Vocab holds the vocabulary word, its translation, a reel caption, and a sample sentence.
+// Vocab holds the vocabulary word, its translation, a reel caption, and a sample sentence,
+// along with the grammar learners need to use the word.
 type Vocab struct {
-	English  string `json:"english"`
-	German   string `json:"german"`
-	Caption  string `json:"caption"`
-	Sentence string `json:"sentence"`
+	// Word is the word in the language being taught, with its article for nouns.
+	Word string `json:"word"`
+	// Translation is the word in the learners' own language.
+	Translation string `json:"translation"`
+	Caption     string `json:"caption"`
+	Sentence    string `json:"sentence"`
+	// SentenceTranslation is the translation of Sentence.
+	SentenceTranslation string `json:"sentence_translation"`
+	// PartOfSpeech is "noun", "verb", "adjective" and so on.
+	PartOfSpeech string `json:"part_of_speech"`
+	// Article is the definite article of a noun, such as der, die or das.
+	Article string `json:"article,omitempty"`
+	// Gender is the grammatical gender of a noun, such as masculine, feminine or neuter.
+	Gender string `json:"gender,omitempty"`
+	// Plural is the plural form of a noun with its article, such as "die Äpfel".
+	Plural string `json:"plural,omitempty"`
+	// IPA is the pronunciation of the word in the International Phonetic Alphabet.
+	IPA string `json:"ipa"`
+	// Praeteritum and PartizipII are the principal parts of a verb, such as
+	// "ging" and "ist gegangen" for "gehen". Only languages with
+	// language.Language.PrincipalParts use them.
+	Praeteritum string `json:"praeteritum,omitempty"`
+	PartizipII  string `json:"partizip_ii,omitempty"`
+	// Request is the targeting the vocab was generated for.
+	Request VocabRequest `json:"request"`
 }
 
-// LoadPostedVocabs loads the list of posted vocab words from the given filename.
-// The file is expected to contain a JSON array of strings. If the file is empty,
-// it returns an empty slice.
-func LoadPostedVocabs(filename string) ([]string, error) {
-	data, err := ioutil.ReadFile(filename)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
-		}
-		return nil, err
+// UnmarshalJSON also accepts the "german", "english" and "sentence_english"
+// keys used before other languages were supported, so old history entries,
+// manifests and decks still load.
+func (v *Vocab) UnmarshalJSON(data []byte) error {
+	type plain Vocab
+	var aux struct {
+		plain
+		German          string `json:"german"`
+		English         string `json:"english"`
+		SentenceEnglish string `json:"sentence_english"`
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	*v = Vocab(aux.plain)
+	if v.Word == "" {
+		v.Word = aux.German
 	}
-	// If file is empty, return an empty slice.
-	if len(data) == 0 {
-		return []string{}, nil
+	if v.Translation == "" {
+		v.Translation = aux.English
 	}
-	var vocabs []string
-	if err := json.Unmarshal(data, &vocabs); err != nil {
-		return nil, err
+	if v.SentenceTranslation == "" {
+		v.SentenceTranslation = aux.SentenceEnglish
 	}
-	return vocabs, nil
+	return nil
 }
 
-// SavePostedVocabs saves the list of posted vocab words to the given filename.
-func SavePostedVocabs(filename string, vocabs []string) error {
-	data, err := json.Marshal(vocabs)
-	if err != nil {
-		return err
+// VocabRequest narrows down the vocabulary GetVocab asks for. Empty fields
+// leave the choice to the model.
+type VocabRequest struct {
+	// Level is a CEFR level: A1, A2, B1, B2, C1 or C2.
+	Level string `json:"level,omitempty"`
+	// Topic is a theme such as "food", "travel" or "office".
+	Topic string `json:"topic,omitempty"`
+	// PartOfSpeech is "noun", "verb", "adjective", "adverb" and so on.
+	PartOfSpeech string `json:"part_of_speech,omitempty"`
+	// Tone sets the voice of the caption and sentence, such as "playful" or "formal".
+	Tone string `json:"tone,omitempty"`
+	// Language is the ISO 639-1 code of the language being taught.
+	// Defaults to language.DefaultTarget.
+	Language string `json:"language,omitempty"`
+	// SourceLanguage is the ISO 639-1 code of the learners' own language,
+	// used for translations. Defaults to language.DefaultSource.
+	SourceLanguage string `json:"source_language,omitempty"`
+}
+
+// languages returns the language taught and the learners' language.
+func (r VocabRequest) languages() (target, source language.Language) {
+	return language.LookupOr(r.Language, language.DefaultTarget), language.LookupOr(r.SourceLanguage, language.DefaultSource)
+}
+
+// Language returns the code of the language the vocab teaches. Vocabs
+// requested without a language, including those posted before languages
+// were configurable, are German.
+func (v Vocab) Language() string {
+	target, _ := v.Request.languages()
+	return target.Code
+}
+
+// LevelAudience describes the learners of a CEFR level: "beginner",
+// "intermediate" or "advanced".
+func LevelAudience(level string) string {
+	switch level {
+	case "A1", "A2":
+		return "beginner"
+	case "B1", "B2":
+		return "intermediate"
+	case "C1", "C2":
+		return "advanced"
 	}
-	return ioutil.WriteFile(filename, data, 0644)
+	return "all"
 }
 
-// UpdatePostedVocabs appends the new word to the list and keeps only the last 50 words.
-func UpdatePostedVocabs(filename, newWord string) error {
-	vocabs, err := LoadPostedVocabs(filename)
-	if err != nil {
-		return err
+// maxAttempts bounds how often GetVocab asks the model to correct an invalid answer.
+const maxAttempts = 3
+
+// vocabKeys are the keys of the JSON object the model answers with.
+var vocabKeys = []string{
+	"translation", "word", "caption", "sentence", "sentence_translation", "part_of_speech",
+	"article", "gender", "plural", "ipa", "praeteritum", "partizip_ii",
+}
+
+// vocabSchema is the JSON schema the model's answer must follow. Structured
+// outputs require every key, so fields that do not apply are empty strings.
+var vocabSchema = func() map[string]interface{} {
+	properties := map[string]interface{}{}
+	for _, key := range vocabKeys {
+		properties[key] = map[string]string{"type": "string"}
 	}
-	// Append new word (assuming uniqueness by English word)
-	vocabs = append(vocabs, newWord)
-	// If the list grows beyond 50, trim the oldest words.
-	if len(vocabs) > 50 {
-		vocabs = vocabs[len(vocabs)-50:]
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             vocabKeys,
+		"additionalProperties": false,
 	}
-	return SavePostedVocabs(filename, vocabs)
+}()
+
+// GetVocab sends prompt to the language model to get a new vocab for req: a
+// word in the language of req, its translation, a reel caption, and a short
+// sentence. The prompt is rendered from a template by the caller; it should
+// ask for the keys listed by VocabKeys and include GrammarInstructions.
+// The answer is requested as structured output and validated; an invalid
+// answer is sent back to the model with the problems found, up to maxAttempts times.
+func GetVocab(ctx context.Context, client llm.Client, req VocabRequest, prompt string) (Vocab, error) {
+	return askVocab(ctx, client, req, prompt)
 }
 
-// GetVocab calls the ChatGPT API to get a new German vocab, its English translation, a reel caption, and a short sentence.
-// It also instructs ChatGPT to avoid words that are already in the posted list.
-func GetVocab(apiKey, postedFile string) (Vocab, error) {
-	// Load the list of posted vocabulary words.
-	postedWords, err := LoadPostedVocabs(postedFile)
-	if err != nil {
-		return Vocab{}, fmt.Errorf("error loading posted vocabs: %v", err)
+// VocabKeys lists the JSON keys a vocab answer must use, as "'a', 'b' and 'c'".
+func VocabKeys() string {
+	return quoteKeys(vocabKeys)
+}
+
+// GrammarInstructions asks for the grammar fields of a vocab as they apply
+// to the languages of req.
+func GrammarInstructions(req VocabRequest) string {
+	return strings.TrimSpace(grammarInstructions(req.languages()))
+}
+
+// grammarInstructions asks for the grammar fields of a vocab as they apply to target.
+func grammarInstructions(target, source language.Language) string {
+	s := fmt.Sprintf("Also give the %s translation of the sentence as 'sentence_translation', the part of speech ", source.Name) +
+		fmt.Sprintf("(noun, verb, adjective, adverb, preposition, conjunction, pronoun or phrase) and the IPA pronunciation of the %s word. ", target.Name)
+	if target.HasArticles() {
+		s += fmt.Sprintf("Always include the definite article with nouns in 'word'. For nouns, give the article (%s), the gender (%s) and the plural with its article. ",
+			language.Or(target.Articles()), language.Or(target.GenderNames()))
+		if len(target.GenderlessArticles) > 0 {
+			s += fmt.Sprintf("The article %s does not always show the gender, so give the gender of the noun itself. ", language.Or(target.GenderlessArticles))
+		}
+	} else {
+		s += fmt.Sprintf("%s has no articles, so leave 'article' and 'gender' empty. For nouns, give the plural. ", target.Name)
 	}
-	excludeList := strings.Join(postedWords, ", ")
-
-	// Build the prompt with instructions:
-	prompt := "Give me a random German vocabulary word with its English translation. " +
-		"Provide a reel caption that includes the German word (with its article when possible) " +
-		"and its English translation, along with hashtags related to German learning. " +
-		"Also provide one sample sentence in German using the word, with each sentence not exceeding 10 words. " +
-		fmt.Sprintf("Do not use the following words: %s. ", excludeList) +
-		"Always include the article with the German word when possible. " +
-		"Return the result in JSON format with keys 'english', 'german', 'caption', and 'sentence'."
-
-	apiURL := "https://api.openai.com/v1/chat/completions"
-	payload := map[string]interface{}{
-		"model": "gpt-3.5-turbo",
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"temperature": 0.7,
+	if target.PrincipalParts != "" {
+		s += "For verbs, give " + target.PrincipalParts + " as 'praeteritum' and 'partizip_ii'. "
+	} else {
+		s += "Leave 'praeteritum' and 'partizip_ii' empty. "
 	}
+	return s
+}
 
-	requestBody, err := json.Marshal(payload)
+// FillVocab completes a partial vocab, such as an entry of a curated word
+// list, by asking the language model for the missing fields. Fields that are already set
+// are kept as they are. The answer is validated like GetVocab's.
+func FillVocab(ctx context.Context, client llm.Client, partial Vocab) (Vocab, error) {
+	target, source := partial.Request.languages()
+	given, err := json.Marshal(partial)
 	if err != nil {
 		return Vocab{}, err
 	}
+	prompt := fmt.Sprintf("Complete this %s vocabulary entry for an Instagram reel: ", target.Name) + string(given) + ". " +
+		"Keep every non-empty value exactly as given and fill in the empty ones. " +
+		fmt.Sprintf("The caption is in %s and includes the word and its translation, along with hashtags related to learning %s. ", source.Name, target.Name) +
+		fmt.Sprintf("The sentence is in %s and uses the word in at most 10 words. ", target.Name) +
+		grammarInstructions(target, source) +
+		"Use empty strings for fields that do not apply. " +
+		"Return the result in JSON format with keys " + quoteKeys(vocabKeys) + "."
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(requestBody))
+	vocab, err := askVocab(ctx, client, partial.Request, prompt)
 	if err != nil {
 		return Vocab{}, err
 	}
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	return mergeVocab(partial, vocab), nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return Vocab{}, err
-	}
-	defer resp.Body.Close()
-
-	var chatResp struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			} `json:"message"`
-		} `json:"choices"`
+// mergeVocab returns filled with every non-empty field of given put back.
+func mergeVocab(given, filled Vocab) Vocab {
+	for _, f := range []struct {
+		dst *string
+		src string
+	}{
+		{&filled.Translation, given.Translation},
+		{&filled.Word, given.Word},
+		{&filled.Caption, given.Caption},
+		{&filled.Sentence, given.Sentence},
+		{&filled.SentenceTranslation, given.SentenceTranslation},
+		{&filled.PartOfSpeech, given.PartOfSpeech},
+		{&filled.Article, given.Article},
+		{&filled.Gender, given.Gender},
+		{&filled.Plural, given.Plural},
+		{&filled.IPA, given.IPA},
+		{&filled.Praeteritum, given.Praeteritum},
+		{&filled.PartizipII, given.PartizipII},
+	} {
+		if f.src != "" {
+			*f.dst = f.src
+		}
 	}
+	return filled
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
-		return Vocab{}, err
-	}
+// askVocab sends prompt to the model and parses its answer as a vocab for
+// req, sending invalid answers back with the problems found up to maxAttempts times.
+func askVocab(ctx context.Context, client llm.Client, req VocabRequest, prompt string) (Vocab, error) {
+	messages := []llm.Message{{Role: "user", Content: prompt}}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		content, err := client.Complete(ctx, messages, &llm.Schema{Name: "vocab", Schema: vocabSchema})
+		if err != nil {
+			return Vocab{}, err
+		}
 
-	if len(chatResp.Choices) == 0 {
-		return Vocab{}, fmt.Errorf("no choices returned from API")
+		var vocab Vocab
+		if err := json.Unmarshal([]byte(llm.StripCodeFence(content)), &vocab); err != nil {
+			lastErr = fmt.Errorf("%w: error parsing vocab JSON: %v", apierror.ErrBadResponse, err)
+			messages = append(messages,
+				llm.Message{Role: "assistant", Content: content},
+				llm.Message{Role: "user", Content: "That was not valid JSON. Return only the JSON object with keys " + quoteKeys(vocabKeys) + "."},
+			)
+			continue
+		}
+		vocab.Request = req
+		if err := vocab.Validate(); err != nil {
+			lastErr = err
+			fmt.Printf("Attempt %d returned an invalid vocab: %v\n", attempt, err)
+			messages = append(messages,
+				llm.Message{Role: "assistant", Content: content},
+				llm.Message{Role: "user", Content: "Your answer has these problems: " + strings.Join(err.(*ValidationError).Problems, "; ") + ". Return a corrected JSON object."},
+			)
+			continue
+		}
+		return vocab, nil
 	}
+	return Vocab{}, fmt.Errorf("no valid vocab after %d attempts: %w", maxAttempts, lastErr)
+}
 
-	// The assistant's message content should be a JSON string.
-	var vocab Vocab
-	if err := json.Unmarshal([]byte(chatResp.Choices[0].Message.Content), &vocab); err != nil {
-		return Vocab{}, err
+// quoteKeys renders keys as "'a', 'b' and 'c'".
+func quoteKeys(keys []string) string {
+	quoted := make([]string, len(keys))
+	for i, k := range keys {
+		quoted[i] = "'" + k + "'"
 	}
-
-	return vocab, nil
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " and " + quoted[len(quoted)-1]
 }
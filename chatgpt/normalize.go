@@ -0,0 +1,53 @@
+package chatgpt
+
+import (
+	"strings"
+	"unicode"
+)
+
+// articles are the leading words stripped by Normalize: German definite and
+// indefinite articles in all cases, English articles and the infinitive "to",
+// and the definite articles of the other supported languages, with the
+// elided "l'" both as written and without its apostrophe, as Normalize sees it.
+var articles = map[string]bool{
+	"der": true, "die": true, "das": true, "den": true, "dem": true, "des": true,
+	"ein": true, "eine": true, "einen": true, "einem": true, "einer": true, "eines": true,
+	"a": true, "an": true, "the": true, "to": true,
+	"el": true, "la": true, "los": true, "las": true, "le": true, "les": true,
+	"il": true, "lo": true, "gli": true, "o": true, "os": true, "de": true, "het": true,
+	"l'": true, "l": true,
+}
+
+// umlauts maps German special letters to the spellings used when they are
+// not available, so "Straße" and "Strasse" compare equal.
+var umlauts = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+// Normalize reduces a word to a form suitable for duplicate
+// checks: lower case, umlauts and ß spelled out, punctuation dropped and a
+// leading article removed. "Die Straße", "strasse" and "eine Strasse" all
+// normalize to "strasse"; "An apple" and "apple" to "apple".
+func Normalize(word string) string {
+	word = umlauts.Replace(strings.ToLower(word))
+	fields := strings.FieldsFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	if len(fields) > 1 && articles[fields[0]] {
+		fields = fields[1:]
+	}
+	return strings.Join(fields, " ")
+}
+
+// SameVocab reports whether a and b name the same word, comparing the
+// normalized words and the normalized translations. Vocabs of different
+// languages never match, so "der Apfel" and "la manzana" may both be "apple".
+func SameVocab(a, b Vocab) bool {
+	if a.Language() != b.Language() {
+		return false
+	}
+	return sameNormalized(a.Word, b.Word) || sameNormalized(a.Translation, b.Translation)
+}
+
+func sameNormalized(a, b string) bool {
+	na := Normalize(a)
+	return na != "" && na == Normalize(b)
+}
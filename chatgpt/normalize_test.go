@@ -0,0 +1,49 @@
+package chatgpt
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"Die Straße", "strasse"},
+		{"strasse", "strasse"},
+		{"eine Strasse", "strasse"},
+		{"An apple", "apple"},
+		{"to go", "go"},
+		{"Äpfel!", "aepfel"},
+		{"l'arbre", "arbre"},
+		{"l’amica", "amica"},
+		{"Guten Morgen", "guten morgen"},
+		{"die", "die"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.word); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestSameVocab(t *testing.T) {
+	de := VocabRequest{Language: "de"}
+	es := VocabRequest{Language: "es"}
+	tests := []struct {
+		name string
+		a, b Vocab
+		want bool
+	}{
+		{"same word", Vocab{Word: "der Apfel", Translation: "apple", Request: de}, Vocab{Word: "Apfel", Translation: "an apple", Request: de}, true},
+		{"same translation", Vocab{Word: "die Straße", Translation: "street", Request: de}, Vocab{Word: "die Gasse", Translation: "the street", Request: de}, true},
+		{"umlaut spelling", Vocab{Word: "die Straße", Request: de}, Vocab{Word: "Strasse", Request: de}, true},
+		{"default language", Vocab{Word: "der Apfel"}, Vocab{Word: "der Apfel", Request: de}, true},
+		{"different words", Vocab{Word: "der Apfel", Translation: "apple", Request: de}, Vocab{Word: "die Birne", Translation: "pear", Request: de}, false},
+		{"different languages", Vocab{Word: "der Apfel", Translation: "apple", Request: de}, Vocab{Word: "la manzana", Translation: "apple", Request: es}, false},
+		{"empty", Vocab{Request: de}, Vocab{Request: de}, false},
+	}
+	for _, tt := range tests {
+		if got := SameVocab(tt.a, tt.b); got != tt.want {
+			t.Errorf("%s: SameVocab = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
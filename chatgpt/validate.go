@@ -0,0 +1,144 @@
+package chatgpt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"vokabelvision/language"
+)
+
+// ErrInvalidVocab is wrapped by ValidationError.
+var ErrInvalidVocab = errors.New("invalid vocab")
+
+// maxSentenceWords is the longest sample sentence that fits on a reel.
+const maxSentenceWords = 10
+
+// ValidationError lists everything wrong with a vocab.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return ErrInvalidVocab.Error() + ": " + strings.Join(e.Problems, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidVocab
+}
+
+// Validate checks that every field that applies is filled in, that nouns
+// carry an article matching their gender and have a plural, that verbs have
+// their principal parts, and that the sample sentence is at most
+// maxSentenceWords words long and uses the word. Verbs are exempt from the
+// last check, since their conjugated forms often share no prefix with the
+// infinitive ("isst" for "essen") or are split up ("stehe ... auf" for
+// "aufstehen"); the verifier checks their sentences instead. If the vocab
+// was requested with a part of speech, it must match. The grammar rules are
+// those of the language in v.Request. It returns a *ValidationError listing
+// all problems.
+func (v Vocab) Validate() error {
+	target, _ := v.Request.languages()
+	var problems []string
+	for _, f := range []struct{ name, value string }{
+		{"translation", v.Translation},
+		{"word", v.Word},
+		{"caption", v.Caption},
+		{"sentence", v.Sentence},
+		{"sentence_translation", v.SentenceTranslation},
+		{"part_of_speech", v.PartOfSpeech},
+		{"ipa", v.IPA},
+	} {
+		if strings.TrimSpace(f.value) == "" {
+			problems = append(problems, "'"+f.name+"' is empty")
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	word, article := splitArticle(v.Word)
+	pos := strings.ToLower(v.PartOfSpeech)
+	if v.Request.PartOfSpeech != "" && pos != strings.ToLower(v.Request.PartOfSpeech) {
+		problems = append(problems, "the word must be a "+v.Request.PartOfSpeech+", not a "+v.PartOfSpeech)
+	}
+	articles := language.Or(target.Articles())
+	if pos == "noun" {
+		if target.HasArticles() {
+			// In every language with articles, nouns must be given with theirs.
+			// Capitalized words that are not nouns, such as "Guten Morgen" or
+			// "Sie", need none.
+			if !target.IsArticle(article) {
+				problems = append(problems, "the noun '"+word+"' must start with its article ("+articles+")")
+			}
+			if !target.IsArticle(v.Article) {
+				problems = append(problems, "'article' must be "+articles)
+			} else if article != "" && language.NormalizeArticle(v.Article) != article {
+				problems = append(problems, "'article' is "+v.Article+" but 'word' starts with "+article)
+			}
+			if g, ok := target.GenderOf(v.Article); ok && strings.ToLower(v.Gender) != g {
+				problems = append(problems, "'gender' must be "+g+" for the article "+v.Article)
+			}
+		}
+		if strings.TrimSpace(v.Plural) == "" {
+			problems = append(problems, "'plural' is empty; use '-' for nouns without a plural")
+		}
+	}
+	if pos == "verb" && target.PrincipalParts != "" {
+		if strings.TrimSpace(v.Praeteritum) == "" {
+			problems = append(problems, "'praeteritum' is empty")
+		}
+		if strings.TrimSpace(v.PartizipII) == "" {
+			problems = append(problems, "'partizip_ii' is empty")
+		}
+	}
+	if n := len(strings.Fields(v.Sentence)); n > maxSentenceWords {
+		problems = append(problems, fmt.Sprintf("the sentence has %d words, more than %d", n, maxSentenceWords))
+	}
+	if pos != "verb" && !sentenceUses(v.Sentence, word) {
+		problems = append(problems, "the sentence does not contain the word '"+word+"'")
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// splitArticle splits a leading article off a word, including elided
+// articles such as the "l'" of "l'arbre".
+func splitArticle(s string) (word, article string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, "'’"); i > 0 {
+		a := language.NormalizeArticle(s[:i] + "'")
+		if articles[a] {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			return strings.TrimSpace(s[i+size:]), a
+		}
+	}
+	fields := strings.Fields(s)
+	if len(fields) > 1 && articles[strings.ToLower(fields[0])] {
+		return strings.Join(fields[1:], " "), strings.ToLower(fields[0])
+	}
+	return strings.TrimSpace(s), ""
+}
+
+// sentenceUses reports whether sentence contains word, allowing for
+// inflected endings by comparing normalized forms by prefix.
+func sentenceUses(sentence, word string) bool {
+	w := Normalize(word)
+	if w == "" {
+		return false
+	}
+	// Compare all but the last letter of longer words so "Haus" matches "Hauses"
+	// and "gehen" matches "gehe".
+	if len(w) > 4 {
+		w = w[:len(w)-1]
+	}
+	for _, token := range strings.Fields(Normalize(sentence)) {
+		if strings.HasPrefix(token, w) {
+			return true
+		}
+	}
+	return strings.Contains(Normalize(sentence), Normalize(word))
+}
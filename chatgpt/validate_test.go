@@ -0,0 +1,108 @@
+package chatgpt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// validVocab returns a German noun that passes Validate.
+func validVocab() Vocab {
+	return Vocab{
+		Word:                "der Apfel",
+		Translation:         "apple",
+		Caption:             "der Apfel = apple",
+		Sentence:            "Der Apfel ist rot.",
+		SentenceTranslation: "The apple is red.",
+		PartOfSpeech:        "noun",
+		Article:             "der",
+		Gender:              "masculine",
+		Plural:              "die Äpfel",
+		IPA:                 "/ˈapfl̩/",
+		Request:             VocabRequest{Language: "de"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(v *Vocab)
+		// problems are substrings of the expected problems; none means valid.
+		problems []string
+	}{
+		{"valid noun", func(v *Vocab) {}, nil},
+		{"empty field", func(v *Vocab) { v.IPA = " " }, []string{"'ipa' is empty"}},
+		{"noun without article", func(v *Vocab) { v.Word = "Apfel" }, []string{"must start with its article"}},
+		{"article mismatch", func(v *Vocab) { v.Article = "die"; v.Gender = "feminine" }, []string{"'article' is die but 'word' starts with der"}},
+		{"wrong gender", func(v *Vocab) { v.Gender = "feminine" }, []string{"'gender' must be masculine"}},
+		{"no plural", func(v *Vocab) { v.Plural = "" }, []string{"'plural' is empty"}},
+		{"sentence without the word", func(v *Vocab) { v.Sentence = "Die Birne ist gelb." }, []string{"does not contain the word 'Apfel'"}},
+		{"inflected noun", func(v *Vocab) {
+			v.Word = "das Haus"
+			v.Article = "das"
+			v.Gender = "neuter"
+			v.Sentence = "Die Farbe des Hauses ist weiß."
+		}, nil},
+		{"long sentence", func(v *Vocab) { v.Sentence = "Der Apfel liegt seit gestern Abend auf dem großen Tisch in der Küche." }, []string{"more than 10"}},
+		{"wrong part of speech", func(v *Vocab) { v.Request.PartOfSpeech = "verb" }, []string{"must be a verb, not a noun"}},
+		{"phrase", func(v *Vocab) {
+			*v = Vocab{Word: "Guten Morgen", Translation: "good morning", Caption: "Guten Morgen!", Sentence: "Guten Morgen, Anna!",
+				SentenceTranslation: "Good morning, Anna!", PartOfSpeech: "phrase", IPA: "/ˈɡuːtn̩ ˈmɔʁɡn̩/", Request: v.Request}
+		}, nil},
+		{"separable verb", func(v *Vocab) {
+			*v = Vocab{Word: "aufstehen", Translation: "to get up", Caption: "aufstehen = to get up", Sentence: "Ich stehe um sieben Uhr auf.",
+				SentenceTranslation: "I get up at seven.", PartOfSpeech: "verb", Praeteritum: "stand auf", PartizipII: "ist aufgestanden",
+				IPA: "/ˈaʊ̯fˌʃteːən/", Request: v.Request}
+		}, nil},
+		{"irregular verb", func(v *Vocab) {
+			*v = Vocab{Word: "essen", Translation: "to eat", Caption: "essen = to eat", Sentence: "Er isst einen Apfel.",
+				SentenceTranslation: "He eats an apple.", PartOfSpeech: "verb", Praeteritum: "aß", PartizipII: "hat gegessen",
+				IPA: "/ˈɛsn̩/", Request: v.Request}
+		}, nil},
+		{"verb without principal parts", func(v *Vocab) {
+			*v = Vocab{Word: "sein", Translation: "to be", Caption: "sein = to be", Sentence: "Das ist gut.",
+				SentenceTranslation: "That is good.", PartOfSpeech: "verb", IPA: "/zaɪ̯n/", Request: v.Request}
+		}, []string{"'praeteritum' is empty", "'partizip_ii' is empty"}},
+		{"elided article", func(v *Vocab) {
+			*v = Vocab{Word: "l'arbre", Translation: "tree", Caption: "l'arbre = tree", Sentence: "L'arbre est grand.",
+				SentenceTranslation: "The tree is tall.", PartOfSpeech: "noun", Article: "l'", Gender: "masculine",
+				Plural: "les arbres", IPA: "/laʁbʁ/", Request: VocabRequest{Language: "fr"}}
+		}, nil},
+		{"typographic apostrophe", func(v *Vocab) {
+			*v = Vocab{Word: "l’amica", Translation: "friend", Caption: "l’amica = friend", Sentence: "L’amica canta.",
+				SentenceTranslation: "The friend sings.", PartOfSpeech: "noun", Article: "l’", Gender: "feminine",
+				Plural: "le amiche", IPA: "/laˈmiːka/", Request: VocabRequest{Language: "it"}}
+		}, nil},
+		{"feminine noun with el", func(v *Vocab) {
+			*v = Vocab{Word: "el agua", Translation: "water", Caption: "el agua = water", Sentence: "El agua está fría.",
+				SentenceTranslation: "The water is cold.", PartOfSpeech: "noun", Article: "el", Gender: "feminine",
+				Plural: "las aguas", IPA: "/el ˈaɣwa/", Request: VocabRequest{Language: "es"}}
+		}, nil},
+		{"wrong gender with la", func(v *Vocab) {
+			*v = Vocab{Word: "la casa", Translation: "house", Caption: "la casa = house", Sentence: "La casa es blanca.",
+				SentenceTranslation: "The house is white.", PartOfSpeech: "noun", Article: "la", Gender: "masculine",
+				Plural: "las casas", IPA: "/la ˈkasa/", Request: VocabRequest{Language: "es"}}
+		}, []string{"'gender' must be feminine"}},
+	}
+	for _, tt := range tests {
+		v := validVocab()
+		tt.change(&v)
+		err := v.Validate()
+		if len(tt.problems) == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !errors.Is(err, ErrInvalidVocab) {
+			t.Errorf("%s: got %v, want a *ValidationError", tt.name, err)
+			continue
+		}
+		for _, p := range tt.problems {
+			if !strings.Contains(err.Error(), p) {
+				t.Errorf("%s: error %q does not mention %q", tt.name, err, p)
+			}
+		}
+	}
+}
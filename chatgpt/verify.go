@@ -0,0 +1,94 @@
+package chatgpt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"vokabelvision/apierror"
+	"vokabelvision/llm"
+)
+
+// Verdicts a verifier can reach.
+const (
+	VerdictCorrect   = "correct"
+	VerdictCorrected = "corrected"
+	VerdictReject    = "reject"
+)
+
+// Verdict is the outcome of VerifyVocab.
+type Verdict struct {
+	// Verdict is VerdictCorrect, VerdictCorrected or VerdictReject.
+	Verdict string `json:"verdict"`
+	// Problems lists what the verifier found wrong.
+	Problems []string `json:"problems,omitempty"`
+	// Corrected is the fixed vocab when Verdict is VerdictCorrected.
+	Corrected *Vocab `json:"corrected,omitempty"`
+}
+
+// verdictSchema is the JSON schema of the verifier's answer.
+var verdictSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"verdict": map[string]interface{}{
+			"type": "string",
+			"enum": []string{VerdictCorrect, VerdictCorrected, VerdictReject},
+		},
+		"problems": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]string{"type": "string"},
+		},
+		"corrected": vocabSchema,
+	},
+	"required":             []string{"verdict", "problems", "corrected"},
+	"additionalProperties": false,
+}
+
+// VerifyVocab asks the model to proofread v: the article, gender and plural
+// of nouns, the verb forms, the translation of the word, and the grammar and
+// translation of the sample sentence, in the languages of v.Request.
+// Fixable mistakes come back corrected; a corrected vocab that fails
+// Validate is rejected.
+func VerifyVocab(ctx context.Context, client llm.Client, v Vocab) (Verdict, error) {
+	given, err := json.Marshal(v)
+	if err != nil {
+		return Verdict{}, err
+	}
+	target, source := v.Request.languages()
+	prompt := fmt.Sprintf("You are a %s teacher proofreading a vocabulary entry for an Instagram reel for %s-speaking learners: ", target.Name, source.Name) + string(given) + ". " +
+		"Check that the article, gender and plural of nouns are correct, that the verb forms are correct, " +
+		fmt.Sprintf("that the %s translation of the word is accurate, that the %s sentence is grammatical and natural, ", source.Name, target.Name) +
+		"and that 'sentence_translation' translates it correctly. " +
+		"Answer with 'verdict' set to '" + VerdictCorrect + "' if everything is right, '" + VerdictCorrected + "' if you fixed mistakes, " +
+		fmt.Sprintf("or '"+VerdictReject+"' if the entry is not a real %s word or cannot be fixed. ", target.Name) +
+		"List every mistake in 'problems'. Put the entry, with your fixes applied, in 'corrected' using the keys " + quoteKeys(vocabKeys) + "."
+
+	content, err := client.Complete(ctx, []llm.Message{{Role: "user", Content: prompt}}, &llm.Schema{Name: "verdict", Schema: verdictSchema})
+	if err != nil {
+		return Verdict{}, err
+	}
+	var verdict Verdict
+	if err := json.Unmarshal([]byte(llm.StripCodeFence(content)), &verdict); err != nil {
+		return Verdict{}, fmt.Errorf("%w: error parsing verdict JSON: %v", apierror.ErrBadResponse, err)
+	}
+	switch verdict.Verdict {
+	case VerdictCorrect:
+		verdict.Corrected = nil
+	case VerdictCorrected:
+		if verdict.Corrected == nil {
+			return Verdict{}, fmt.Errorf("%w: verdict %q without a corrected vocab", apierror.ErrBadResponse, verdict.Verdict)
+		}
+		verdict.Corrected.Request = v.Request
+		if err := verdict.Corrected.Validate(); err != nil {
+			verdict.Verdict = VerdictReject
+			verdict.Problems = append(verdict.Problems, "the correction is invalid: "+err.Error())
+		}
+	case VerdictReject:
+	default:
+		return Verdict{}, fmt.Errorf("%w: unknown verdict %q", apierror.ErrBadResponse, verdict.Verdict)
+	}
+	if verdict.Verdict == VerdictReject {
+		verdict.Corrected = nil
+	}
+	return verdict, nil
+}
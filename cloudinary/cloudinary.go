@@ -3,27 +3,27 @@ package cloudinary
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/cloudinary/cloudinary-go/v2"
 	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
-)
 
-func UploadVideo(cloudinaryURL, videoPath string) (string, string) {
-	ctx := context.Background()
+	"vokabelvision/apierror"
+)
 
+// UploadVideo uploads the video at videoPath and returns its secure URL and public ID.
+func UploadVideo(ctx context.Context, cloudinaryURL, videoPath string) (string, string, error) {
 	// Initialize Cloudinary from the CLOUDINARY_URL environment variable.
 	// The CLOUDINARY_URL format is: cloudinary://<api_key>:<api_secret>@<cloud_name>
 	cld, err := cloudinary.NewFromURL(cloudinaryURL)
 	if err != nil {
-		log.Fatalf("failed to create Cloudinary instance: %v", err)
+		return "", "", fmt.Errorf("%w: failed to create Cloudinary instance: %v", apierror.ErrAuth, err)
 	}
 
 	// Open the video file to upload.
 	file, err := os.Open(videoPath)
 	if err != nil {
-		log.Fatalf("failed to open file: %v", err)
+		return "", "", fmt.Errorf("failed to open file: %w", err)
 	}
 	defer file.Close()
 
@@ -34,21 +34,24 @@ func UploadVideo(cloudinaryURL, videoPath string) (string, string) {
 		// Optionally, you can set PublicID or other parameters here.
 	})
 	if err != nil {
-		log.Fatalf("failed to upload video: %v", err)
+		return "", "", fmt.Errorf("failed to upload video: %w", err)
+	}
+	// API errors are reported in the result rather than as an error.
+	if resp.Error.Message != "" {
+		return "", "", fmt.Errorf("failed to upload video: %w: %s", apierror.ErrBadResponse, resp.Error.Message)
 	}
 
 	// Print the secure URL returned from Cloudinary.
 	fmt.Printf("Upload successful! Secure URL: %s\n", resp.SecureURL)
-	return resp.SecureURL, resp.PublicID
+	return resp.SecureURL, resp.PublicID, nil
 }
 
-func DeleteVideo(cloudinaryURL, publicID string) {
-	ctx := context.Background()
-
+// DeleteVideo removes the uploaded video with the given public ID.
+func DeleteVideo(ctx context.Context, cloudinaryURL, publicID string) error {
 	// Initialize Cloudinary using the CLOUDINARY_URL environment variable.
 	cld, err := cloudinary.NewFromURL(cloudinaryURL)
 	if err != nil {
-		log.Fatalf("failed to create Cloudinary instance: %v", err)
+		return fmt.Errorf("%w: failed to create Cloudinary instance: %v", apierror.ErrAuth, err)
 	}
 
 	// Call the Destroy function specifying the ResourceType as "video".
@@ -57,8 +60,12 @@ func DeleteVideo(cloudinaryURL, publicID string) {
 		ResourceType: "video",
 	})
 	if err != nil {
-		log.Fatalf("failed to delete video: %v", err)
+		return fmt.Errorf("failed to delete video: %w", err)
+	}
+	if result.Error.Message != "" {
+		return fmt.Errorf("failed to delete video: %w: %s", apierror.ErrBadResponse, result.Error.Message)
 	}
 
 	fmt.Printf("Delete result: %#v\n", result)
+	return nil
 }
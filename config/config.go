@@ -2,18 +2,219 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
+
+	"vokabelvision/cost"
+	"vokabelvision/language"
+	"vokabelvision/leonardo"
 )
 
 // Config holds the API keys and other configuration settings.
 type Config struct {
-	ChatGPTAPIKey        string `json:"chatgpt_api_key"`
-	LeonardoAPIKey       string `json:"leonardo_api_key"`
-	ElevenLabsAPIKey     string `json:"elevenlabs_api_key"`
-	ElevenLabsVoiceID    string `json:"elevenlabs_voice_id"`
+	ChatGPTAPIKey        string    `json:"chatgpt_api_key"`
+	LeonardoAPIKey       string    `json:"leonardo_api_key"`
+	ElevenLabsAPIKey     string    `json:"elevenlabs_api_key"`
+	ElevenLabsVoiceID    string    `json:"elevenlabs_voice_id"`
+	InstagramUserID      string    `json:"instagram_user_id"`
+	InstagramAccessToken string    `json:"instagram_access_token"`
+	CloudinaryURL        string    `json:"cloudinary_url"`
+	LLM                  LLM       `json:"llm"`
+	Providers            Providers `json:"providers"`
+	Verify               Verify    `json:"verify"`
+	// ElevenLabsVoices maps language codes to the voice used for reels in
+	// that language. Languages without an entry use ElevenLabsVoiceID.
+	ElevenLabsVoices map[string]string `json:"elevenlabs_voices"`
+	Caption          Caption           `json:"caption"`
+	Moderation       Moderation        `json:"moderation"`
+	// VocabCandidates is how many vocabs to ask the language model for at
+	// once. The best-scoring one is used and the rest are kept in BacklogFile
+	// for later slots. Zero or one asks for a single vocab.
+	VocabCandidates int `json:"vocab_candidates"`
+	// BacklogFile is the JSON Lines file of unused candidates. Defaults to "backlog.jsonl".
+	BacklogFile string `json:"backlog_file"`
+	// DeckFile is the curated word list (.csv, .tsv or .json) used by the
+	// "deck" vocab provider.
+	DeckFile string `json:"deck_file"`
+	// PromptsDir holds the prompt templates vocab.tmpl and image.tmpl.
+	// Missing files use the built-in prompts. Defaults to "prompts".
+	PromptsDir string `json:"prompts_dir"`
+	// Costs configures the ledger of API usage, its unit prices and the monthly budget.
+	Costs Costs `json:"costs"`
+	// Image holds the Leonardo generation settings. A profile can override them.
+	Image leonardo.ImageOptions `json:"image"`
+	// VideoFontFile is the TrueType font for the text drawn on reels. Empty uses ffmpeg's default font.
+	VideoFontFile string `json:"video_font_file"`
+	// RunsDir holds one working directory per run. Defaults to "runs".
+	RunsDir string `json:"runs_dir"`
+	// HistoryFile is the JSON Lines file that records every posted reel. Defaults to "history.jsonl".
+	HistoryFile string `json:"history_file"`
+	// PreviewDir receives the reel and caption of dry runs. Defaults to "previews".
+	PreviewDir string `json:"preview_dir"`
+	// RequireApproval puts generated reels in a review queue; only approved
+	// reels are published at the next scheduled slot.
+	RequireApproval bool `json:"require_approval"`
+	// AdminAddr is the listen address of the admin API and dashboard, such as
+	// "127.0.0.1:8080". Empty disables the admin server.
+	AdminAddr string `json:"admin_addr"`
+	// AdminToken must be sent as a bearer token to pause, resume or trigger
+	// the schedule through the admin API. It may only be empty when AdminAddr
+	// is a loopback address.
+	AdminToken string `json:"admin_token"`
+	// RunTimeout bounds a single run, as a Go duration such as "20m". Empty means no deadline.
+	RunTimeout string `json:"run_timeout"`
+	// Schedules lists the slots the scheduler posts at. Defaults to 07:00,
+	// 13:00 and 19:00 Berlin time.
+	Schedules []Schedule `json:"schedules"`
+	// Accounts holds Instagram credentials by name for profiles that post to
+	// an account other than the default one.
+	Accounts map[string]Account `json:"accounts"`
+}
+
+// Schedule is one entry of the posting schedule.
+type Schedule struct {
+	// Cron is the cron spec (minute hour day month day-of-week).
+	Cron string `json:"cron"`
+	// Timezone is an IANA time zone name such as "Europe/Berlin". Defaults to local time.
+	Timezone string `json:"timezone"`
+	// Jitter delays each run by a random duration up to this Go duration, such as "15m".
+	Jitter  string  `json:"jitter"`
+	Profile Profile `json:"profile"`
+}
+
+// Profile describes the content produced at a slot and where it is posted.
+type Profile struct {
+	// Level is the CEFR level of the vocabulary, such as "A1" or "B2".
+	Level string `json:"level,omitempty"`
+	// Theme is the topic of the vocabulary, such as "food" or "travel".
+	Theme string `json:"theme,omitempty"`
+	// PartOfSpeech restricts the vocabulary to "noun", "verb", "adjective" and so on.
+	PartOfSpeech string `json:"part_of_speech,omitempty"`
+	// Tone sets the voice of the caption and sentence, such as "playful".
+	Tone string `json:"tone,omitempty"`
+	// Language is the ISO 639-1 code of the language taught, such as "es".
+	// Defaults to German.
+	Language string `json:"language,omitempty"`
+	// SourceLanguage is the ISO 639-1 code of the language translations and
+	// captions are written in. Defaults to English.
+	SourceLanguage string `json:"source_language,omitempty"`
+	// Account names an entry of Config.Accounts. Empty posts to the default account.
+	Account string `json:"account,omitempty"`
+	// Image overrides the non-zero fields of Config.Image for this profile.
+	Image *leonardo.ImageOptions `json:"image,omitempty"`
+}
+
+// Account holds the Instagram credentials of one account.
+type Account struct {
 	InstagramUserID      string `json:"instagram_user_id"`
 	InstagramAccessToken string `json:"instagram_access_token"`
-	CloudinaryURL        string `json:"cloudinary_url"`
+}
+
+// LLM configures the language model used for vocab generation. Empty
+// fields use the provider's defaults.
+type LLM struct {
+	// Provider is "openai" (the default), "openai-compatible" for local
+	// servers, "anthropic" or "gemini".
+	Provider string `json:"provider"`
+	// APIKey defaults to chatgpt_api_key for OpenAI.
+	APIKey string `json:"api_key"`
+	// BaseURL of an OpenAI-compatible API, such as "http://localhost:11434/v1".
+	BaseURL     string   `json:"base_url"`
+	Model       string   `json:"model"`
+	Temperature *float64 `json:"temperature"`
+	MaxTokens   int      `json:"max_tokens"`
+	// Organization is sent as the OpenAI-Organization header.
+	Organization string `json:"organization"`
+	// ResponseFormat is "json_schema" (default), "json_object" or "text" for
+	// OpenAI-compatible servers without structured output support.
+	ResponseFormat string `json:"response_format"`
+	// Fallbacks are tried in order when this endpoint fails.
+	Fallbacks []LLM `json:"fallbacks,omitempty"`
+}
+
+// Caption configures how reel captions are built. Zero fields use the defaults.
+type Caption struct {
+	// TemplateFile is a text/template file for the caption. Empty uses the built-in template.
+	TemplateFile string `json:"template_file"`
+	// HashtagPools are drawn from at random for captions matching their level, theme and language.
+	HashtagPools []HashtagPool `json:"hashtag_pools"`
+	// MaxHashtags defaults to Instagram's limit of 30.
+	MaxHashtags int `json:"max_hashtags"`
+	// MaxLength defaults to Instagram's limit of 2200 characters.
+	MaxLength int `json:"max_length"`
+	// CallToAction is an optional line such as "Follow for a new word every day!".
+	CallToAction string `json:"call_to_action"`
+}
+
+// HashtagPool is a set of hashtags for captions of one level, theme and
+// language. Empty fields match every caption.
+type HashtagPool struct {
+	Level    string   `json:"level"`
+	Theme    string   `json:"theme"`
+	Language string   `json:"language"`
+	Hashtags []string `json:"hashtags"`
+}
+
+// Verify configures the second-pass check of every vocab for correct
+// grammar and translations before media is generated for it.
+type Verify struct {
+	Enabled bool `json:"enabled"`
+	// Model overrides llm.model for verification, so a stronger model can check a cheaper one.
+	Model string `json:"model"`
+}
+
+// Moderation configures the check of every vocab and caption before it is
+// used on a public account.
+type Moderation struct {
+	// Provider is "openai" for the OpenAI moderation endpoint plus the
+	// blocklists, "local" for the blocklists alone, or empty to disable moderation.
+	Provider string `json:"provider"`
+	// Model is the OpenAI moderation model. Defaults to "omni-moderation-latest".
+	Model string `json:"model"`
+	// BaseURL is the OpenAI-compatible API the moderation endpoint is called
+	// on. Defaults to "https://api.openai.com/v1".
+	BaseURL string `json:"base_url"`
+	// BlockedWords are words and phrases that must not appear in the word,
+	// its sentence or the caption.
+	BlockedWords []string `json:"blocked_words"`
+	// BlockedTopics are subjects, such as "religion", the reel must not be
+	// about. The language model of the llm section decides whether it is.
+	BlockedTopics []string `json:"blocked_topics"`
+}
+
+// Costs configures the cost ledger.
+type Costs struct {
+	// LedgerFile is the JSON Lines file of API usage. Defaults to "costs.jsonl".
+	LedgerFile string `json:"ledger_file"`
+	// Currency labels the costs in reports, such as "USD".
+	Currency string `json:"currency"`
+	// Prices maps "provider/model" or "provider" to its unit prices.
+	// Usage without a price is recorded at no cost.
+	Prices cost.Prices `json:"prices"`
+	// MonthlyBudget stops image and speech generation and skips verification
+	// once the calendar month's costs reach it. Zero means no budget.
+	MonthlyBudget float64 `json:"monthly_budget"`
+}
+
+// Providers selects the implementation used for each pipeline stage.
+// An empty name selects the default provider for that stage.
+type Providers struct {
+	Vocab     string `json:"vocab"`
+	Image     string `json:"image"`
+	Speech    string `json:"speech"`
+	Video     string `json:"video"`
+	Host      string `json:"host"`
+	Publisher string `json:"publisher"`
+}
+
+// validLevel reports whether level is empty or a CEFR level.
+func validLevel(level string) bool {
+	switch level {
+	case "", "A1", "A2", "B1", "B2", "C1", "C2":
+		return true
+	}
+	return false
 }
 
 // LoadConfig reads the configuration from the given file.
@@ -29,5 +230,74 @@ func LoadConfig(filename string) (Config, error) {
 	if err := decoder.Decode(&cfg); err != nil {
 		return Config{}, err
 	}
+	if cfg.RunsDir == "" {
+		cfg.RunsDir = "runs"
+	}
+	if cfg.PreviewDir == "" {
+		cfg.PreviewDir = "previews"
+	}
+	if cfg.PromptsDir == "" {
+		cfg.PromptsDir = "prompts"
+	}
+	if cfg.BacklogFile == "" {
+		cfg.BacklogFile = "backlog.jsonl"
+	}
+	if cfg.Costs.LedgerFile == "" {
+		cfg.Costs.LedgerFile = "costs.jsonl"
+	}
+	if cfg.HistoryFile == "" {
+		cfg.HistoryFile = "history.jsonl"
+	}
+	if len(cfg.Schedules) == 0 {
+		cfg.Schedules = []Schedule{{Cron: "0 7,13,19 * * *", Timezone: "Europe/Berlin"}}
+	}
+	for name, a := range cfg.Accounts {
+		if a.InstagramUserID == "" || a.InstagramAccessToken == "" {
+			return Config{}, fmt.Errorf("account %q is missing Instagram credentials", name)
+		}
+	}
+	if cfg.AdminAddr != "" && cfg.AdminToken == "" && !loopback(cfg.AdminAddr) {
+		return Config{}, fmt.Errorf("admin_token is required when admin_addr %q is not a loopback address", cfg.AdminAddr)
+	}
+	if err := cfg.Image.Validate(); err != nil {
+		return Config{}, fmt.Errorf("image: %v", err)
+	}
+	for i, s := range cfg.Schedules {
+		if s.Profile.Image != nil {
+			if err := cfg.Image.Merge(*s.Profile.Image).Validate(); err != nil {
+				return Config{}, fmt.Errorf("schedule %d image: %v", i, err)
+			}
+		}
+		if !validLevel(s.Profile.Level) {
+			return Config{}, fmt.Errorf("schedule %d has invalid CEFR level %q", i, s.Profile.Level)
+		}
+		for _, code := range []string{s.Profile.Language, s.Profile.SourceLanguage} {
+			if code == "" {
+				continue
+			}
+			if _, err := language.Lookup(code); err != nil {
+				return Config{}, fmt.Errorf("schedule %d: %v", i, err)
+			}
+		}
+		if s.Profile.Account != "" {
+			if _, ok := cfg.Accounts[s.Profile.Account]; !ok {
+				return Config{}, fmt.Errorf("schedule %d refers to unknown account %q", i, s.Profile.Account)
+			}
+		}
+	}
 	return cfg, nil
 }
+
+// loopback reports whether the listen address addr only accepts connections
+// from this machine, such as "127.0.0.1:8080" or "localhost:8080".
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,104 @@
+package cost
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Usage is what one call to a paid API consumed.
+type Usage struct {
+	Time     time.Time `json:"time"`
+	RunID    string    `json:"run_id,omitempty"`
+	Stage    string    `json:"stage,omitempty"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model,omitempty"`
+	// InputTokens and OutputTokens are counted by language models.
+	InputTokens  int `json:"input_tokens,omitempty"`
+	OutputTokens int `json:"output_tokens,omitempty"`
+	// Credits are API credits, as charged by Leonardo.
+	Credits int `json:"credits,omitempty"`
+	// Characters are the characters of text synthesized into speech.
+	Characters int `json:"characters,omitempty"`
+	// Cost is the price of the usage at the time it was recorded.
+	Cost float64 `json:"cost"`
+}
+
+// Price holds the unit prices of a provider or model.
+type Price struct {
+	InputPerMillion       float64 `json:"input_per_million"`
+	OutputPerMillion      float64 `json:"output_per_million"`
+	PerCredit             float64 `json:"per_credit"`
+	PerThousandCharacters float64 `json:"per_thousand_characters"`
+}
+
+// Prices maps "provider/model" or "provider" to its unit prices, such as
+// "openai/gpt-4o-mini" or "leonardo". A model's own price takes precedence.
+type Prices map[string]Price
+
+// Cost returns the price of u. Usage without a configured price is free.
+func (p Prices) Cost(u Usage) float64 {
+	price, ok := p[u.Provider+"/"+u.Model]
+	if !ok {
+		price = p[u.Provider]
+	}
+	return float64(u.InputTokens)*price.InputPerMillion/1e6 +
+		float64(u.OutputTokens)*price.OutputPerMillion/1e6 +
+		float64(u.Credits)*price.PerCredit +
+		float64(u.Characters)*price.PerThousandCharacters/1e3
+}
+
+// Meter prices the usage of one stage of a run and writes it to the ledger.
+type Meter struct {
+	RunID  string
+	Stage  string
+	Prices Prices
+	// Ledger receives every usage. Nil only keeps the total.
+	Ledger *Ledger
+
+	mu    sync.Mutex
+	total float64
+}
+
+// Add prices u, stamps it with the meter's run and stage and records it.
+// A failure to write the ledger is logged, since the API call it describes
+// has already succeeded.
+func (m *Meter) Add(u Usage) {
+	if u.Time.IsZero() {
+		u.Time = time.Now()
+	}
+	u.RunID = m.RunID
+	u.Stage = m.Stage
+	u.Cost = m.Prices.Cost(u)
+	m.mu.Lock()
+	m.total += u.Cost
+	m.mu.Unlock()
+	if m.Ledger != nil {
+		if err := m.Ledger.Append(u); err != nil {
+			log.Printf("Failed to record %s usage: %v", u.Provider, err)
+		}
+	}
+}
+
+// Total returns the cost of everything added so far.
+func (m *Meter) Total() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
+
+type meterKey struct{}
+
+// WithMeter returns a context whose API calls are recorded on m.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// Record adds u to the meter of ctx. It does nothing when ctx has no meter,
+// so API clients can report usage without knowing whether anyone listens.
+func Record(ctx context.Context, u Usage) {
+	if m, ok := ctx.Value(meterKey{}).(*Meter); ok {
+		m.Add(u)
+	}
+}
@@ -0,0 +1,108 @@
+package cost
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"vokabelvision/jsonl"
+)
+
+// Ledger is an append-only JSON Lines file of API usage.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// OpenLedger returns the ledger backed by the file at path. The file is
+// created on the first Append.
+func OpenLedger(path string) *Ledger {
+	return &Ledger{path: path}
+}
+
+// Append adds a usage record to the end of the ledger.
+func (l *Ledger) Append(u Usage) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return jsonl.Append(l.path, u)
+}
+
+// All returns every usage record, oldest first.
+func (l *Ledger) All() ([]Usage, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return jsonl.Read[Usage](l.path)
+}
+
+// Since returns the total cost of the usage recorded at or after t.
+func (l *Ledger) Since(t time.Time) (float64, error) {
+	usages, err := l.All()
+	if err != nil {
+		return 0, err
+	}
+	var total float64
+	for _, u := range usages {
+		if !u.Time.Before(t) {
+			total += u.Cost
+		}
+	}
+	return total, nil
+}
+
+// MonthStart returns the start of the calendar month of t in t's location.
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// Line is one row of a cost report: the usage of one provider in one period.
+type Line struct {
+	Period       string
+	Provider     string
+	InputTokens  int
+	OutputTokens int
+	Credits      int
+	Characters   int
+	Cost         float64
+}
+
+// Report groups usages by period and provider. by is "day", "month" or
+// "run". Lines are sorted by period, then provider.
+func Report(usages []Usage, by string) ([]Line, error) {
+	var period func(Usage) string
+	switch by {
+	case "day":
+		period = func(u Usage) string { return u.Time.Local().Format("2006-01-02") }
+	case "month":
+		period = func(u Usage) string { return u.Time.Local().Format("2006-01") }
+	case "run":
+		period = func(u Usage) string { return u.RunID }
+	default:
+		return nil, fmt.Errorf("unknown report period %q", by)
+	}
+	lines := map[[2]string]*Line{}
+	for _, u := range usages {
+		key := [2]string{period(u), u.Provider}
+		l, ok := lines[key]
+		if !ok {
+			l = &Line{Period: key[0], Provider: key[1]}
+			lines[key] = l
+		}
+		l.InputTokens += u.InputTokens
+		l.OutputTokens += u.OutputTokens
+		l.Credits += u.Credits
+		l.Characters += u.Characters
+		l.Cost += u.Cost
+	}
+	report := make([]Line, 0, len(lines))
+	for _, l := range lines {
+		report = append(report, *l)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Period != report[j].Period {
+			return report[i].Period < report[j].Period
+		}
+		return report[i].Provider < report[j].Provider
+	})
+	return report, nil
+}
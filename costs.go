@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"vokabelvision/config"
+	"vokabelvision/cost"
+)
+
+const costsUsage = "Usage: vokabelvision costs [day | month | run]"
+
+// CostsCommand prints the API usage and costs from the cost ledger, grouped
+// by day, month (the default) or run, and the spending against the monthly budget.
+func CostsCommand(args []string) error {
+	by := "month"
+	switch len(args) {
+	case 0:
+	case 1:
+		by = args[0]
+	default:
+		return errors.New(costsUsage)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	ledger := cost.OpenLedger(cfg.Costs.LedgerFile)
+	usages, err := ledger.All()
+	if err != nil {
+		return fmt.Errorf("error reading cost ledger: %w", err)
+	}
+	report, err := cost.Report(usages, by)
+	if err != nil {
+		return fmt.Errorf("%v\n%s", err, costsUsage)
+	}
+	if len(report) == 0 {
+		fmt.Println("No API usage has been recorded yet.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "%s\tprovider\tinput tokens\toutput tokens\tcredits\tcharacters\tcost %s\t\n", by, cfg.Costs.Currency)
+	var total float64
+	for _, l := range report {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%.4f\t\n", l.Period, l.Provider, l.InputTokens, l.OutputTokens, l.Credits, l.Characters, l.Cost)
+		total += l.Cost
+	}
+	fmt.Fprintf(w, "total\t\t\t\t\t\t%.4f\t\n", total)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if cfg.Costs.MonthlyBudget > 0 {
+		spent, err := ledger.Since(cost.MonthStart(time.Now()))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nThis month: %.2f of the %.2f %s budget spent\n", spent, cfg.Costs.MonthlyBudget, cfg.Costs.Currency)
+	}
+	return nil
+}
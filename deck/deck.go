@@ -0,0 +1,158 @@
+package deck
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"vokabelvision/chatgpt"
+)
+
+// ErrExhausted is returned by Next when every entry of the deck was used.
+var ErrExhausted = errors.New("deck exhausted")
+
+// Deck is a curated list of vocabulary, posted in file order.
+type Deck struct {
+	Path  string
+	Cards []chatgpt.Vocab
+}
+
+// Open loads the deck at path.
+func Open(path string) (*Deck, error) {
+	cards, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return nil, fmt.Errorf("deck %s is empty", path)
+	}
+	return &Deck{Path: path, Cards: cards}, nil
+}
+
+// Next returns the first card at the given CEFR level and in the given
+// language for which used reports false. Cards without a level or language
+// match every level or language, and empty arguments match every card.
+func (d *Deck) Next(level, lang string, used func(chatgpt.Vocab) (bool, error)) (chatgpt.Vocab, error) {
+	for _, card := range d.Cards {
+		if level != "" && card.Request.Level != "" && card.Request.Level != level {
+			continue
+		}
+		if lang != "" && card.Request.Language != "" && card.Request.Language != lang {
+			continue
+		}
+		u, err := used(card)
+		if err != nil {
+			return chatgpt.Vocab{}, err
+		}
+		if !u {
+			return card, nil
+		}
+	}
+	return chatgpt.Vocab{}, fmt.Errorf("%w: every entry of %s has been used", ErrExhausted, d.Path)
+}
+
+// Load reads a deck of vocabulary from a .csv, .tsv or .json file, in file order.
+//
+// CSV and TSV files need a header row naming the columns. Columns are the
+// JSON keys of chatgpt.Vocab (word, translation, article, plural, sentence,
+// sentence_translation, part_of_speech, ipa, ...; german and english are
+// accepted for word and translation) plus "level" for the CEFR level and
+// "language" for the ISO 639-1 code of the word; unknown columns are
+// ignored. JSON files hold an array of objects with the same keys. A word
+// given without its article gets the article column prepended.
+func Load(path string) ([]chatgpt.Vocab, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rows []map[string]string
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		rows, err = readTable(f, ',')
+	case ".tsv":
+		rows, err = readTable(f, '\t')
+	case ".json":
+		err = json.NewDecoder(f).Decode(&rows)
+	default:
+		return nil, fmt.Errorf("unsupported deck format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading deck %s: %v", path, err)
+	}
+
+	cards := make([]chatgpt.Vocab, 0, len(rows))
+	for i, row := range rows {
+		card, err := parseCard(row)
+		if err != nil {
+			return nil, fmt.Errorf("deck %s, entry %d: %v", path, i+1, err)
+		}
+		cards = append(cards, card)
+	}
+	return cards, nil
+}
+
+// readTable reads a delimited file with a header row into one map per row.
+func readTable(r io.Reader, comma rune) ([]map[string]string, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(header[i]))
+	}
+	var rows []map[string]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := map[string]string{}
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+// parseCard turns one deck row into a vocab.
+func parseCard(row map[string]string) (chatgpt.Vocab, error) {
+	// Round-trip through JSON so the columns map onto Vocab's JSON keys.
+	data, err := json.Marshal(row)
+	if err != nil {
+		return chatgpt.Vocab{}, err
+	}
+	var card chatgpt.Vocab
+	if err := json.Unmarshal(data, &card); err != nil {
+		return chatgpt.Vocab{}, err
+	}
+	if card.Word == "" || card.Translation == "" {
+		return chatgpt.Vocab{}, fmt.Errorf("word and translation are required")
+	}
+	card.Request.Level = strings.ToUpper(row["level"])
+	card.Request.Language = strings.ToLower(row["language"])
+	if card.Article != "" && !strings.HasPrefix(strings.ToLower(card.Word), strings.ToLower(card.Article)+" ") {
+		card.Word = card.Article + " " + card.Word
+	}
+	if card.Article != "" && card.PartOfSpeech == "" {
+		card.PartOfSpeech = "noun"
+	}
+	return card, nil
+}
@@ -2,19 +2,28 @@ package elevenlabs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"vokabelvision/apierror"
+	"vokabelvision/cost"
 )
 
-// GetAudio calls the ElevenLabs text-to-speech API to generate German pronunciation audio.
+// GetAudio calls the ElevenLabs text-to-speech API to generate pronunciation audio.
+// The multilingual model speaks the language of text; voiceID selects the speaker.
 // It uses the voice endpoint and logs errors if the response isn't OK.
-func GetAudio(apiKey, text string, sentence string, voiceID string) (string, error) {
+// The audio is written to audioPath.
+func GetAudio(ctx context.Context, apiKey, text string, sentence string, voiceID string, audioPath string) (string, error) {
 	// Update the endpoint to match ElevenLabs' TTS API.
 	// Replace "german_voice" with your actual voice ID if different.
 	apiURL := "https://api.elevenlabs.io/v1/text-to-speech/" + voiceID
+	modelID := "eleven_multilingual_v2"
 
 	pausedText := fmt.Sprintf(`
 			<speak>
@@ -30,14 +39,14 @@ func GetAudio(apiKey, text string, sentence string, voiceID string) (string, err
 	// Some endpoints might require additional fields such as a model_id.
 	payload := map[string]interface{}{
 		"text":     pausedText,
-		"model_id": "eleven_multilingual_v2", // Uncomment if needed per documentation.
+		"model_id": modelID, // Uncomment if needed per documentation.
 	}
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return "", err
 	}
 
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(body))
 	if err != nil {
 		return "", err
 	}
@@ -56,11 +65,17 @@ func GetAudio(apiKey, text string, sentence string, voiceID string) (string, err
 	// Check for errors.
 	if resp.StatusCode != http.StatusOK {
 		responseBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("ElevenLabs API error: status %d, response: %s", resp.StatusCode, string(responseBytes))
+		return "", apierror.FromStatus("ElevenLabs", resp.StatusCode, responseBytes)
+	}
+
+	// ElevenLabs reports the characters it bills in the character-cost header.
+	characters, err := strconv.Atoi(resp.Header.Get("character-cost"))
+	if err != nil {
+		characters = utf8.RuneCountInString(pausedText)
 	}
+	cost.Record(ctx, cost.Usage{Provider: "elevenlabs", Model: modelID, Characters: characters})
 
 	// This endpoint typically streams audio directly.
-	audioPath := "vocab_audio.mp3"
 	out, err := os.Create(audioPath)
 	if err != nil {
 		return "", err
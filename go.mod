@@ -3,9 +3,12 @@ module vokabelvision
 go 1.23.1
 
 require (
-	github.com/cloudinary/cloudinary-go/v2 v2.9.1 // indirect
+	github.com/cloudinary/cloudinary-go/v2 v2.9.1
+	github.com/robfig/cron/v3 v3.0.1
+)
+
+require (
 	github.com/creasty/defaults v1.7.0 // indirect
 	github.com/google/uuid v1.5.0 // indirect
 	github.com/gorilla/schema v1.4.1 // indirect
-	github.com/robfig/cron/v3 v3.0.1 // indirect
 )
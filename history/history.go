@@ -0,0 +1,152 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/jsonl"
+)
+
+// Entry is one posted reel.
+type Entry struct {
+	RunID    string        `json:"run_id,omitempty"`
+	PostedAt time.Time     `json:"posted_at"`
+	Account  string        `json:"account,omitempty"`
+	Vocab    chatgpt.Vocab `json:"vocab"`
+	MediaID  string        `json:"media_id,omitempty"`
+	Prompt   string        `json:"prompt,omitempty"`
+	Voice    string        `json:"voice,omitempty"`
+	// Cost is the total cost of the run in the configured currency.
+	Cost float64 `json:"cost,omitempty"`
+}
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	Since   time.Time
+	Until   time.Time
+	Account string
+	// Word matches entries whose word or translation contains it, ignoring
+	// case, articles and umlaut spellings.
+	Word string
+}
+
+// Store is an append-only JSON Lines file of posted reels.
+type Store struct {
+	path string
+	mu   sync.Mutex
+}
+
+// Open returns the store backed by the file at path. The file is created on
+// the first Append.
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Append adds an entry to the end of the history.
+func (s *Store) Append(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonl.Append(s.path, e)
+}
+
+// All returns every entry, oldest first.
+func (s *Store) All() ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return jsonl.Read[Entry](s.path)
+}
+
+// Query returns the entries matching f, oldest first.
+func (s *Store) Query(f Filter) ([]Entry, error) {
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	word := chatgpt.Normalize(f.Word)
+	var matches []Entry
+	for _, e := range all {
+		if !f.Since.IsZero() && e.PostedAt.Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && !e.PostedAt.Before(f.Until) {
+			continue
+		}
+		if f.Account != "" && e.Account != f.Account {
+			continue
+		}
+		if word != "" && !strings.Contains(chatgpt.Normalize(e.Vocab.Word), word) &&
+			!strings.Contains(chatgpt.Normalize(e.Vocab.Translation), word) {
+			continue
+		}
+		matches = append(matches, e)
+	}
+	return matches, nil
+}
+
+// Recent returns the translations of the last n posted words in the given
+// language, oldest first. lang is a language code such as "de".
+func (s *Store) Recent(n int, lang string) ([]string, error) {
+	all, err := s.All()
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, e := range all {
+		if e.Vocab.Language() == lang {
+			words = append(words, e.Vocab.Translation)
+		}
+	}
+	if len(words) > n {
+		words = words[len(words)-n:]
+	}
+	return words, nil
+}
+
+// Find returns the first entry that names the same word as v, matching the
+// word and its translation with chatgpt.SameVocab, and reports whether there
+// was one. Only entries in the language of v are compared.
+func (s *Store) Find(v chatgpt.Vocab) (Entry, bool, error) {
+	all, err := s.All()
+	if err != nil {
+		return Entry{}, false, err
+	}
+	for _, e := range all {
+		if chatgpt.SameVocab(e.Vocab, v) {
+			return e, true, nil
+		}
+	}
+	return Entry{}, false, nil
+}
+
+// ImportPostedVocabs seeds an empty history from the legacy posted-vocab file,
+// a JSON array of English words. It does nothing if the history already has
+// entries or the legacy file does not exist, and returns the number of words imported.
+func (s *Store) ImportPostedVocabs(filename string) (int, error) {
+	if _, err := os.Stat(s.path); err == nil {
+		return 0, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var words []string
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &words); err != nil {
+			return 0, fmt.Errorf("error parsing %s: %v", filename, err)
+		}
+	}
+	for _, w := range words {
+		if err := s.Append(Entry{Vocab: chatgpt.Vocab{Translation: w}}); err != nil {
+			return 0, err
+		}
+	}
+	return len(words), nil
+}
@@ -0,0 +1,140 @@
+package imagecheck
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+)
+
+const (
+	// minStdDev is the luminance standard deviation below which a frame
+	// counts as blank or near-uniform.
+	minStdDev = 10
+	// textBand is the share of the frame at the bottom the reel's text is drawn on.
+	textBand = 0.35
+	// step is the distance in pixels between sampled pixels.
+	step = 2
+)
+
+// Options are what an image must match. Zero fields are not checked.
+type Options struct {
+	MinWidth  int
+	MinHeight int
+	// AspectRatio is the expected width divided by height, such as 9.0/16.
+	AspectRatio float64
+	// AspectTolerance is the allowed relative deviation from AspectRatio. Defaults to 0.02.
+	AspectTolerance float64
+}
+
+// Result is the outcome of checking one image.
+type Result struct {
+	Path   string `json:"path"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+	// StdDev is the standard deviation of the luminance, 0-255.
+	StdDev float64 `json:"std_dev"`
+	// BandBrightness is the mean luminance of the text band, 0-255, and
+	// BandBusyness the mean luminance change between horizontally adjacent pixels in it.
+	// The white overlay text reads best on a dark, calm band.
+	BandBrightness float64 `json:"band_brightness"`
+	BandBusyness   float64 `json:"band_busyness"`
+	// Problems lists the failed checks; an image with problems is not used
+	// unless a reviewer chooses it.
+	Problems []string `json:"problems,omitempty"`
+	// Score ranks the images that pass, from 0 to 1.
+	Score  float64 `json:"score"`
+	Chosen bool    `json:"chosen,omitempty"`
+}
+
+// Passed reports whether the image passed every check.
+func (r Result) Passed() bool {
+	return len(r.Problems) == 0
+}
+
+// Check decodes the JPEG or PNG image at path and checks its resolution,
+// aspect ratio, whether it is blank, and how well text would read on the
+// band at the bottom of the frame.
+func Check(path string, opts Options) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return Result{}, fmt.Errorf("error decoding %s: %v", path, err)
+	}
+
+	b := img.Bounds()
+	r := Result{Path: path, Width: b.Dx(), Height: b.Dy()}
+	if r.Width == 0 || r.Height == 0 {
+		r.Problems = append(r.Problems, "the image is empty")
+		return r, nil
+	}
+	if r.Width < opts.MinWidth || r.Height < opts.MinHeight {
+		r.Problems = append(r.Problems, fmt.Sprintf("resolution %dx%d is below %dx%d", r.Width, r.Height, opts.MinWidth, opts.MinHeight))
+	}
+	if opts.AspectRatio > 0 {
+		tolerance := opts.AspectTolerance
+		if tolerance <= 0 {
+			tolerance = 0.02
+		}
+		ratio := float64(r.Width) / float64(r.Height)
+		if math.Abs(ratio-opts.AspectRatio)/opts.AspectRatio > tolerance {
+			r.Problems = append(r.Problems, fmt.Sprintf("aspect ratio %.3f differs from %.3f", ratio, opts.AspectRatio))
+		}
+	}
+
+	var sum, sumSq, n float64
+	var bandSum, bandDiff, bandN float64
+	bandTop := b.Max.Y - int(float64(r.Height)*textBand)
+	for y := b.Min.Y; y < b.Max.Y; y += step {
+		for x := b.Min.X; x < b.Max.X; x += step {
+			l := luminance(img, x, y)
+			sum += l
+			sumSq += l * l
+			n++
+			if y >= bandTop && x+1 < b.Max.X {
+				bandSum += l
+				bandDiff += math.Abs(l - luminance(img, x+1, y))
+				bandN++
+			}
+		}
+	}
+	mean := sum / n
+	r.StdDev = math.Sqrt(math.Max(0, sumSq/n-mean*mean))
+	if bandN > 0 {
+		r.BandBrightness = bandSum / bandN
+		r.BandBusyness = bandDiff / bandN
+	}
+	if r.StdDev < minStdDev {
+		r.Problems = append(r.Problems, "the image is blank or nearly uniform")
+	}
+
+	// Favour detailed images whose text band is dark and calm.
+	r.Score = 0.4*math.Min(r.StdDev/64, 1) +
+		0.3*(1-r.BandBrightness/255) +
+		0.3*(1-math.Min(r.BandBusyness/32, 1))
+	return r, nil
+}
+
+// Best returns the index of the highest scoring image that passed every
+// check, or -1 if none did.
+func Best(results []Result) int {
+	best := -1
+	for i, r := range results {
+		if r.Passed() && (best < 0 || r.Score > results[best].Score) {
+			best = i
+		}
+	}
+	return best
+}
+
+// luminance returns the perceived brightness of a pixel, 0-255.
+func luminance(img image.Image, x, y int) float64 {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+}
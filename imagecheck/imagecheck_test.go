@@ -0,0 +1,144 @@
+package imagecheck
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePNG saves a w x h image whose pixels are given by gray.
+func writePNG(t *testing.T, w, h int, gray func(x, y int) uint8) string {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{Y: gray(x, y)})
+		}
+	}
+	path := filepath.Join(t.TempDir(), "image.png")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// gradient is a detailed picture with a dark, calm bottom band.
+func gradient(h int) func(x, y int) uint8 {
+	return func(x, y int) uint8 {
+		if y >= h*2/3 {
+			return 20
+		}
+		return uint8((x*7 + y*3) % 256)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	reel := Options{MinWidth: 90, MinHeight: 160, AspectRatio: 9.0 / 16}
+	tests := []struct {
+		name string
+		w, h int
+		gray func(x, y int) uint8
+		opts Options
+		// problems are substrings of the expected problems; none means it passes.
+		problems []string
+	}{
+		{"good image", 90, 160, gradient(160), reel, nil},
+		{"no options", 30, 30, gradient(30), Options{}, nil},
+		{"too small", 45, 80, gradient(80), reel, []string{"resolution 45x80 is below 90x160"}},
+		{"wrong aspect ratio", 160, 160, gradient(160), reel, []string{"aspect ratio 1.000 differs from 0.562"}},
+		{"within tolerance", 91, 160, gradient(160), reel, nil},
+		{"blank", 90, 160, func(x, y int) uint8 { return 128 }, reel, []string{"blank"}},
+	}
+	for _, tt := range tests {
+		r, err := Check(writePNG(t, tt.w, tt.h, tt.gray), tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if r.Width != tt.w || r.Height != tt.h {
+			t.Errorf("%s: size %dx%d, want %dx%d", tt.name, r.Width, r.Height, tt.w, tt.h)
+		}
+		if r.Passed() != (len(tt.problems) == 0) {
+			t.Errorf("%s: problems %q, want %q", tt.name, r.Problems, tt.problems)
+		}
+		for _, p := range tt.problems {
+			if !strings.Contains(strings.Join(r.Problems, "; "), p) {
+				t.Errorf("%s: problems %q do not mention %q", tt.name, r.Problems, p)
+			}
+		}
+		if r.Score < 0 || r.Score > 1 {
+			t.Errorf("%s: score %v is not between 0 and 1", tt.name, r.Score)
+		}
+	}
+}
+
+func TestCheckTextBand(t *testing.T) {
+	const w, h = 90, 160
+	calm, err := Check(writePNG(t, w, h, gradient(h)), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bright, err := Check(writePNG(t, w, h, func(x, y int) uint8 {
+		if y >= h*2/3 {
+			return 240
+		}
+		return gradient(h)(x, y)
+	}), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Alternating columns, which sampling every other pixel alone would miss.
+	busy, err := Check(writePNG(t, w, h, func(x, y int) uint8 {
+		if y >= h*2/3 {
+			return uint8(x % 2 * 200)
+		}
+		return gradient(h)(x, y)
+	}), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bright.BandBrightness <= calm.BandBrightness || bright.Score >= calm.Score {
+		t.Errorf("a bright band (%.1f, score %.2f) should score below a dark one (%.1f, score %.2f)",
+			bright.BandBrightness, bright.Score, calm.BandBrightness, calm.Score)
+	}
+	if busy.BandBusyness <= calm.BandBusyness || busy.Score >= calm.Score {
+		t.Errorf("a busy band (%.1f, score %.2f) should score below a calm one (%.1f, score %.2f)",
+			busy.BandBusyness, busy.Score, calm.BandBusyness, calm.Score)
+	}
+}
+
+func TestCheckDecodeError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Check(path, Options{}); err == nil {
+		t.Error("Check accepted a file that is not an image")
+	}
+}
+
+func TestBest(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []Result
+		want    int
+	}{
+		{"none", nil, -1},
+		{"highest score", []Result{{Score: 0.4}, {Score: 0.8}, {Score: 0.6}}, 1},
+		{"skips failed", []Result{{Score: 0.4}, {Score: 0.9, Problems: []string{"blank"}}}, 0},
+		{"all failed", []Result{{Score: 0.9, Problems: []string{"blank"}}}, -1},
+	}
+	for _, tt := range tests {
+		if got := Best(tt.results); got != tt.want {
+			t.Errorf("%s: Best = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
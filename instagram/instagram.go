@@ -2,18 +2,21 @@ package instagram
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
+
+	"vokabelvision/apierror"
 )
 
 // PublishVideo uploads and publishes a video as a Reel using the Instagram Graph API.
 // videoURL must be publicly accessible. igUserID and bearerToken are required for authentication.
-// caption is optional.
-func PublishVideo(igUserID, bearerToken, videoURL, caption string) error {
+// caption is optional. It returns the ID of the published media.
+func PublishVideo(ctx context.Context, igUserID, bearerToken, videoURL, caption string) (string, error) {
 	client := &http.Client{}
 
 	// Step 1: Create a media container for the video.
@@ -25,37 +28,37 @@ func PublishVideo(igUserID, bearerToken, videoURL, caption string) error {
 	}
 	containerBody, err := json.Marshal(containerPayload)
 	if err != nil {
-		return fmt.Errorf("error marshalling container payload: %v", err)
+		return "", fmt.Errorf("error marshalling container payload: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", containerURL, bytes.NewBuffer(containerBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", containerURL, bytes.NewBuffer(containerBody))
 	if err != nil {
-		return fmt.Errorf("error creating container request: %v", err)
+		return "", fmt.Errorf("error creating container request: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+bearerToken)
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error creating media container: %v", err)
+		return "", fmt.Errorf("error creating media container: %w", err)
 	}
 	defer resp.Body.Close()
 
 	containerRespBytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("error reading container response: %v", err)
+		return "", fmt.Errorf("error reading container response: %v", err)
 	}
 
 	fmt.Printf("Container creation response (status %d): %s\n", resp.StatusCode, string(containerRespBytes))
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("error creating media container: status %d, response: %s", resp.StatusCode, string(containerRespBytes))
+		return "", fmt.Errorf("error creating media container: %w", apierror.FromStatus("Instagram", resp.StatusCode, containerRespBytes))
 	}
 
 	var containerResp struct {
 		ID string `json:"id"`
 	}
 	if err := json.Unmarshal(containerRespBytes, &containerResp); err != nil {
-		return fmt.Errorf("error parsing container response: %v", err)
+		return "", fmt.Errorf("%w: error parsing container response: %v", apierror.ErrBadResponse, err)
 	}
 
 	// Step 2: Publish the media container with retry.
@@ -65,7 +68,7 @@ func PublishVideo(igUserID, bearerToken, videoURL, caption string) error {
 	}
 	publishBody, err := json.Marshal(publishPayload)
 	if err != nil {
-		return fmt.Errorf("error marshalling publish payload: %v", err)
+		return "", fmt.Errorf("error marshalling publish payload: %v", err)
 	}
 
 	var publishRespBytes []byte
@@ -74,22 +77,22 @@ func PublishVideo(igUserID, bearerToken, videoURL, caption string) error {
 	var pubResp *http.Response
 
 	for i := 0; i < maxRetries; i++ {
-		req2, err := http.NewRequest("POST", publishURL, bytes.NewBuffer(publishBody))
+		req2, err := http.NewRequestWithContext(ctx, "POST", publishURL, bytes.NewBuffer(publishBody))
 		if err != nil {
-			return fmt.Errorf("error creating publish request: %v", err)
+			return "", fmt.Errorf("error creating publish request: %v", err)
 		}
 		req2.Header.Set("Content-Type", "application/json")
 		req2.Header.Set("Authorization", "Bearer "+bearerToken)
 
 		pubResp, err = client.Do(req2)
 		if err != nil {
-			return fmt.Errorf("error publishing media container: %v", err)
+			return "", fmt.Errorf("error publishing media container: %w", err)
 		}
 
 		publishRespBytes, err = ioutil.ReadAll(pubResp.Body)
 		pubResp.Body.Close()
 		if err != nil {
-			return fmt.Errorf("error reading publish response: %v", err)
+			return "", fmt.Errorf("error reading publish response: %v", err)
 		}
 
 		fmt.Printf("Attempt %d - Media publish response (status %d): %s\n", i+1, pubResp.StatusCode, string(publishRespBytes))
@@ -100,21 +103,28 @@ func PublishVideo(igUserID, bearerToken, videoURL, caption string) error {
 		}
 
 		// Wait before the next retry.
-		time.Sleep(delay)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
 	}
 
 	// Final check after retry loop.
-	if pubResp.StatusCode != http.StatusOK || strings.Contains(string(publishRespBytes), "Media ID is not available") {
-		return fmt.Errorf("error publishing media container after retries: status %d, response: %s", pubResp.StatusCode, string(publishRespBytes))
+	if strings.Contains(string(publishRespBytes), "Media ID is not available") {
+		return "", fmt.Errorf("%w waiting for media container to be ready: %s", apierror.ErrTimeout, string(publishRespBytes))
+	}
+	if pubResp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error publishing media container after retries: %w", apierror.FromStatus("Instagram", pubResp.StatusCode, publishRespBytes))
 	}
 
 	var publishResp struct {
 		ID string `json:"id"`
 	}
 	if err := json.Unmarshal(publishRespBytes, &publishResp); err != nil {
-		return fmt.Errorf("error parsing publish response: %v", err)
+		return "", fmt.Errorf("%w: error parsing publish response: %v", apierror.ErrBadResponse, err)
 	}
 
 	fmt.Printf("Video published with ID: %s\n", publishResp.ID)
-	return nil
+	return publishResp.ID, nil
 }
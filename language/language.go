@@ -0,0 +1,196 @@
+package language
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Language holds what the pipeline needs to know about a language it
+// teaches or translates into.
+type Language struct {
+	// Code is the ISO 639-1 code, such as "de".
+	Code string
+	// Name is the English name of the language, such as "German".
+	Name string
+	// Genders maps each singular definite article to the grammatical gender
+	// it marks. It is empty for languages without articles.
+	Genders map[string]string
+	// GenderlessArticles are definite articles that do not tell the gender
+	// of their noun: elided forms such as French and Italian "l'", written
+	// without a space before the noun, and articles that nouns of another
+	// gender also take, such as Spanish "el" in "el agua".
+	GenderlessArticles []string
+	// PrincipalParts describes the verb forms learners are shown besides the
+	// infinitive, filling Vocab.Praeteritum and Vocab.PartizipII. Empty for
+	// languages where they are not asked for.
+	PrincipalParts string
+	// Hashtags are the language-learning hashtags added to captions.
+	Hashtags []string
+}
+
+// Defaults for profiles that do not set a language.
+const (
+	DefaultTarget = "de"
+	DefaultSource = "en"
+)
+
+var languages = map[string]Language{
+	"de": {
+		Code:           "de",
+		Name:           "German",
+		Genders:        map[string]string{"der": "masculine", "die": "feminine", "das": "neuter"},
+		PrincipalParts: "the Präteritum (3rd person singular) and the Partizip II with its auxiliary, such as 'ging' and 'ist gegangen'",
+		Hashtags:       []string{"#german", "#germanlanguage", "#learngerman", "#deutschlernen"},
+	},
+	"en": {
+		Code:     "en",
+		Name:     "English",
+		Hashtags: []string{"#english", "#englishlanguage", "#learnenglish"},
+	},
+	"es": {
+		Code:    "es",
+		Name:    "Spanish",
+		Genders: map[string]string{"el": "masculine", "la": "feminine"},
+		// Feminine nouns starting with a stressed a take "el": "el agua".
+		GenderlessArticles: []string{"el"},
+		Hashtags:           []string{"#spanish", "#spanishlanguage", "#learnspanish", "#aprenderespanol"},
+	},
+	"fr": {
+		Code:               "fr",
+		Name:               "French",
+		Genders:            map[string]string{"le": "masculine", "la": "feminine"},
+		GenderlessArticles: []string{"l'"},
+		Hashtags:           []string{"#french", "#frenchlanguage", "#learnfrench", "#apprendrelefrancais"},
+	},
+	"it": {
+		Code:               "it",
+		Name:               "Italian",
+		Genders:            map[string]string{"il": "masculine", "lo": "masculine", "la": "feminine"},
+		GenderlessArticles: []string{"l'"},
+		Hashtags:           []string{"#italian", "#italianlanguage", "#learnitalian", "#imparareitaliano"},
+	},
+	"pt": {
+		Code:     "pt",
+		Name:     "Portuguese",
+		Genders:  map[string]string{"o": "masculine", "a": "feminine"},
+		Hashtags: []string{"#portuguese", "#portugueselanguage", "#learnportuguese"},
+	},
+	"nl": {
+		Code:     "nl",
+		Name:     "Dutch",
+		Genders:  map[string]string{"de": "common", "het": "neuter"},
+		Hashtags: []string{"#dutch", "#dutchlanguage", "#learndutch", "#nederlandsleren"},
+	},
+	"tr": {
+		Code:     "tr",
+		Name:     "Turkish",
+		Hashtags: []string{"#turkish", "#turkishlanguage", "#learnturkish", "#türkçeöğren"},
+	},
+}
+
+// Lookup returns the language with the given ISO 639-1 code.
+func Lookup(code string) (Language, error) {
+	l, ok := languages[strings.ToLower(code)]
+	if !ok {
+		return Language{}, fmt.Errorf("unsupported language %q (supported: %s)", code, strings.Join(Codes(), ", "))
+	}
+	return l, nil
+}
+
+// LookupOr is like Lookup but returns the language def for an empty or
+// unsupported code. def must be a supported code.
+func LookupOr(code, def string) Language {
+	if code == "" {
+		code = def
+	}
+	if l, err := Lookup(code); err == nil {
+		return l
+	}
+	return languages[def]
+}
+
+// Codes lists the supported language codes in alphabetical order.
+func Codes() []string {
+	codes := make([]string, 0, len(languages))
+	for c := range languages {
+		codes = append(codes, c)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// HasArticles reports whether nouns are learned with a definite article.
+func (l Language) HasArticles() bool {
+	return len(l.Genders) > 0
+}
+
+// Articles lists the definite articles of l in alphabetical order.
+func (l Language) Articles() []string {
+	articles := make([]string, 0, len(l.Genders)+len(l.GenderlessArticles))
+	for a := range l.Genders {
+		articles = append(articles, a)
+	}
+	for _, a := range l.GenderlessArticles {
+		if _, ok := l.Genders[a]; !ok {
+			articles = append(articles, a)
+		}
+	}
+	sort.Strings(articles)
+	return articles
+}
+
+// GenderNames lists the grammatical genders of l in alphabetical order.
+func (l Language) GenderNames() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, g := range l.Genders {
+		if !seen[g] {
+			seen[g] = true
+			names = append(names, g)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsArticle reports whether a is a definite article of l.
+func (l Language) IsArticle(a string) bool {
+	a = NormalizeArticle(a)
+	if _, ok := l.Genders[a]; ok {
+		return true
+	}
+	for _, g := range l.GenderlessArticles {
+		if g == a {
+			return true
+		}
+	}
+	return false
+}
+
+// GenderOf returns the gender the article a marks. ok is false when a is
+// not an article of l or does not tell the gender of its noun.
+func (l Language) GenderOf(a string) (gender string, ok bool) {
+	a = NormalizeArticle(a)
+	for _, g := range l.GenderlessArticles {
+		if g == a {
+			return "", false
+		}
+	}
+	gender, ok = l.Genders[a]
+	return gender, ok
+}
+
+// NormalizeArticle lower-cases an article and spells a typographic
+// apostrophe, as in "l’", as "'".
+func NormalizeArticle(a string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(a)), "’", "'")
+}
+
+// Or joins words as "a, b or c".
+func Or(words []string) string {
+	if len(words) <= 1 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " or " + words[len(words)-1]
+}
@@ -2,6 +2,7 @@ package leonardo
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,60 +10,42 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
-)
 
-// GeneratePrompt creates a Leonardo.ai prompt using the English and German words.
-func GeneratePrompt(english, german, sentence string) string {
-	promptTemplate := fmt.Sprintf(`Create an image
-		Display a picture of an %s set against a solid background. Ensure that the background color contrasts with the typical color of an %s (i.e. do not use a red background if the %s is red). Picture should in square with white border.
-		Below %s picture, Show the word "%s" in a prominent font.`, english, english, english, english, german)
-	prompt := strings.ReplaceAll(promptTemplate, "[word]", english)
-	prompt = strings.ReplaceAll(prompt, "[german_translation]", german)
-	return prompt
-}
+	"vokabelvision/apierror"
+	"vokabelvision/cost"
+)
 
-// GetImage calls the Leonardo.ai API using the prompt and downloads the generated image.
-func GetImage(apiKey, prompt string) (string, error) {
+// GetImages calls the Leonardo.ai API using the prompt and the generation
+// options and downloads the generated images next to imagePath, numbered
+// from 1: image-1.jpg, image-2.jpg and so on. word is the vocab the images
+// are for and seeds the generation when opts.SeedPolicy is SeedWord.
+func GetImages(ctx context.Context, apiKey, prompt, word, imagePath string, opts ImageOptions) ([]string, error) {
 	apiURL := "https://cloud.leonardo.ai/api/rest/v1/generations" // Correct endpoint
-	// Replace "prompt" with "textPrompts" as required by the API:
-	payload := map[string]interface{}{
-		"modelId":       "6b645e3a-d64f-4341-a6d8-7a3690fbf042",
-		"contrast":      3.5,
-		"prompt":        prompt,
-		"num_images":    1,
-		"width":         1080,
-		"height":        1920,
-		"alchemy":       true,
-		"styleUUID":     "111dc692-d470-4eec-b791-3475abac4c46",
-		"enhancePrompt": false,
-		"seed":          "8933646694",
-	}
+	payload := opts.payload(prompt, word)
 	body, err := json.Marshal(payload)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	log.Println(string(body))
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(body))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	req.Header.Set("accept", "application/json")
 	req.Header.Set("authorization", "Bearer "+apiKey)
 	req.Header.Set("content-type", "application/json")
-	log.Println("authorization", "Bearer "+apiKey)
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
-	log.Println(resp)
 	// Check for error status
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := ioutil.ReadAll(resp.Body)
-		return "", fmt.Errorf("Leonardo API error: status %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return nil, apierror.FromStatus("Leonardo", resp.StatusCode, bodyBytes)
 	}
 
 	// Assume the API returns JSON with an sdGenerationJob field containing the generationId.
@@ -73,42 +56,69 @@ func GetImage(apiKey, prompt string) (string, error) {
 		} `json:"sdGenerationJob"`
 	}
 	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return "", err
+		return nil, fmt.Errorf("%w: error decoding Leonardo response: %v", apierror.ErrBadResponse, err)
+	}
+	if res.SDGenerationJob.GenerationId == "" {
+		return nil, fmt.Errorf("%w: Leonardo returned no generation ID", apierror.ErrBadResponse)
 	}
-	log.Println(res)
 
-	// Now poll the API using the generationId to get the image URL.
+	cost.Record(ctx, cost.Usage{
+		Provider: "leonardo",
+		Model:    payload["modelId"].(string),
+		Credits:  res.SDGenerationJob.APICreditCost,
+	})
+
+	// Now poll the API using the generationId to get the image URLs.
 	generationId := res.SDGenerationJob.GenerationId
 	log.Printf("Generation ID: %s", generationId)
-	imageURL, err := pollForImage(apiKey, generationId)
+	imageURLs, err := pollForImages(ctx, apiKey, generationId)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	// Download the image.
-	imageResp, err := http.Get(imageURL)
-	if err != nil {
-		return "", err
+	ext := filepath.Ext(imagePath)
+	base := strings.TrimSuffix(imagePath, ext)
+	var paths []string
+	for i, imageURL := range imageURLs {
+		path := fmt.Sprintf("%s-%d%s", base, i+1, ext)
+		if err := download(ctx, client, imageURL, path); err != nil {
+			return nil, err
+		}
+		fmt.Println("Image saved to", path)
+		paths = append(paths, path)
 	}
-	defer imageResp.Body.Close()
+	return paths, nil
+}
 
-	imagePath := "vocab_image.jpg"
-	out, err := os.Create(imagePath)
+// download saves the image at url to path.
+func download(ctx context.Context, client *http.Client, url, path string) error {
+	imageReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, imageResp.Body)
+	imageResp, err := client.Do(imageReq)
 	if err != nil {
-		return "", err
+		return err
+	}
+	defer imageResp.Body.Close()
+	if imageResp.StatusCode != http.StatusOK {
+		bodyBytes, _ := ioutil.ReadAll(imageResp.Body)
+		return apierror.FromStatus("Leonardo CDN", imageResp.StatusCode, bodyBytes)
 	}
 
-	fmt.Println("Image saved to", imagePath)
-	return imagePath, nil
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, imageResp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
 }
 
-func pollForImage(apiKey, generationId string) (string, error) {
+// pollForImages waits for the generation to complete and returns the URLs of its images.
+func pollForImages(ctx context.Context, apiKey, generationId string) ([]string, error) {
 	apiURL := fmt.Sprintf("https://cloud.leonardo.ai/api/rest/v1/generations/%s", generationId)
 	client := &http.Client{}
 
@@ -116,16 +126,21 @@ func pollForImage(apiKey, generationId string) (string, error) {
 	delay := 5 * time.Second
 
 	for i := 0; i < maxRetries; i++ {
-		req, err := http.NewRequest("GET", apiURL, nil)
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		req.Header.Set("accept", "application/json")
 		req.Header.Set("authorization", "Bearer "+apiKey)
 
 		resp, err := client.Do(req)
 		if err != nil {
-			return "", err
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			return nil, apierror.FromStatus("Leonardo", resp.StatusCode, bodyBytes)
 		}
 
 		var pollRes struct {
@@ -140,21 +155,34 @@ func pollForImage(apiKey, generationId string) (string, error) {
 		// Decode the response and close the body
 		if err := json.NewDecoder(resp.Body).Decode(&pollRes); err != nil {
 			resp.Body.Close()
-			return "", err
+			return nil, fmt.Errorf("%w: error decoding Leonardo poll response: %v", apierror.ErrBadResponse, err)
 		}
 		resp.Body.Close()
 
+		if pollRes.GenerationsByPK.Status == "FAILED" {
+			return nil, fmt.Errorf("%w: Leonardo generation %s failed", apierror.ErrContentRejected, generationId)
+		}
+
 		// Check if the job is complete and at least one image is available
-		if pollRes.GenerationsByPK.Status == "COMPLETE" && len(pollRes.GenerationsByPK.GeneratedImages) > 0 {
-			imageUrl := pollRes.GenerationsByPK.GeneratedImages[0].URL
-			if imageUrl != "" {
-				return imageUrl, nil
+		if pollRes.GenerationsByPK.Status == "COMPLETE" {
+			var imageURLs []string
+			for _, img := range pollRes.GenerationsByPK.GeneratedImages {
+				if img.URL != "" {
+					imageURLs = append(imageURLs, img.URL)
+				}
+			}
+			if len(imageURLs) > 0 {
+				return imageURLs, nil
 			}
 		}
 
 		// Wait before the next poll
-		time.Sleep(delay)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
 	}
 
-	return "", fmt.Errorf("timed out waiting for image generation")
+	return nil, fmt.Errorf("%w waiting for image generation", apierror.ErrTimeout)
 }
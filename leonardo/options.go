@@ -0,0 +1,159 @@
+package leonardo
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// Seed policies for ImageOptions.SeedPolicy.
+const (
+	// SeedRandom lets Leonardo pick a new seed for every image.
+	SeedRandom = "random"
+	// SeedFixed uses ImageOptions.Seed for every image.
+	SeedFixed = "fixed"
+	// SeedWord derives the seed from the word, so regenerating the image of
+	// a word gives a similar composition while different words differ.
+	SeedWord = "word"
+)
+
+// Defaults used for zero ImageOptions fields.
+const (
+	DefaultModelID   = "6b645e3a-d64f-4341-a6d8-7a3690fbf042"
+	DefaultStyleUUID = "111dc692-d470-4eec-b791-3475abac4c46"
+	DefaultContrast  = 3.5
+	DefaultWidth     = 1080
+	DefaultHeight    = 1920
+	// DefaultNumImages is more than one so the best image can be picked.
+	DefaultNumImages = 4
+	// maxNumImages is the most images Leonardo generates in one request.
+	maxNumImages = 8
+)
+
+// ImageOptions are the generation settings sent to Leonardo. Zero fields
+// use the defaults above.
+type ImageOptions struct {
+	ModelID   string  `json:"model_id,omitempty"`
+	StyleUUID string  `json:"style_uuid,omitempty"`
+	Contrast  float64 `json:"contrast,omitempty"`
+	// Width and Height must be multiples of 8. The reel is 1080x1920.
+	Width  int `json:"width,omitempty"`
+	Height int `json:"height,omitempty"`
+	// Alchemy defaults to true when nil.
+	Alchemy        *bool  `json:"alchemy,omitempty"`
+	EnhancePrompt  *bool  `json:"enhance_prompt,omitempty"`
+	NegativePrompt string `json:"negative_prompt,omitempty"`
+	// SeedPolicy is SeedRandom (the default), SeedFixed or SeedWord.
+	SeedPolicy string `json:"seed_policy,omitempty"`
+	// Seed is used with SeedFixed.
+	Seed int64 `json:"seed,omitempty"`
+	// NumImages is how many images to generate per request, up to 8.
+	// Defaults to DefaultNumImages; 1 turns off picking the best image.
+	NumImages int `json:"num_images,omitempty"`
+}
+
+// Merge returns o with every non-zero field of override applied, such as
+// the image settings of a profile over those of the config.
+func (o ImageOptions) Merge(override ImageOptions) ImageOptions {
+	if override.ModelID != "" {
+		o.ModelID = override.ModelID
+	}
+	if override.StyleUUID != "" {
+		o.StyleUUID = override.StyleUUID
+	}
+	if override.Contrast != 0 {
+		o.Contrast = override.Contrast
+	}
+	if override.Width != 0 {
+		o.Width = override.Width
+	}
+	if override.Height != 0 {
+		o.Height = override.Height
+	}
+	if override.Alchemy != nil {
+		o.Alchemy = override.Alchemy
+	}
+	if override.EnhancePrompt != nil {
+		o.EnhancePrompt = override.EnhancePrompt
+	}
+	if override.NegativePrompt != "" {
+		o.NegativePrompt = override.NegativePrompt
+	}
+	if override.SeedPolicy != "" {
+		o.SeedPolicy = override.SeedPolicy
+	}
+	if override.Seed != 0 {
+		o.Seed = override.Seed
+	}
+	if override.NumImages != 0 {
+		o.NumImages = override.NumImages
+	}
+	return o
+}
+
+// Validate checks the sizes, the number of images and the seed policy.
+func (o ImageOptions) Validate() error {
+	if o.Width < 0 || o.Height < 0 || o.Width%8 != 0 || o.Height%8 != 0 {
+		return fmt.Errorf("image size %dx%d must be multiples of 8", o.Width, o.Height)
+	}
+	if o.NumImages < 0 || o.NumImages > maxNumImages {
+		return fmt.Errorf("num_images must be between 1 and %d, or 0 for the default of %d, not %d", maxNumImages, DefaultNumImages, o.NumImages)
+	}
+	switch o.SeedPolicy {
+	case "", SeedRandom, SeedWord:
+	case SeedFixed:
+		if o.Seed <= 0 {
+			return fmt.Errorf("seed_policy %q needs a positive seed", SeedFixed)
+		}
+	default:
+		return fmt.Errorf("unknown seed_policy %q", o.SeedPolicy)
+	}
+	return nil
+}
+
+// payload builds the generation request for prompt. word is the vocab the
+// image is for; it seeds the generation with SeedWord.
+func (o ImageOptions) payload(prompt, word string) map[string]interface{} {
+	p := map[string]interface{}{
+		"modelId":       or(o.ModelID, DefaultModelID),
+		"styleUUID":     or(o.StyleUUID, DefaultStyleUUID),
+		"contrast":      DefaultContrast,
+		"prompt":        prompt,
+		"num_images":    DefaultNumImages,
+		"width":         DefaultWidth,
+		"height":        DefaultHeight,
+		"alchemy":       o.Alchemy == nil || *o.Alchemy,
+		"enhancePrompt": o.EnhancePrompt != nil && *o.EnhancePrompt,
+	}
+	if o.Contrast != 0 {
+		p["contrast"] = o.Contrast
+	}
+	if o.NumImages > 0 {
+		p["num_images"] = o.NumImages
+	}
+	if o.Width > 0 {
+		p["width"] = o.Width
+	}
+	if o.Height > 0 {
+		p["height"] = o.Height
+	}
+	if o.NegativePrompt != "" {
+		p["negative_prompt"] = o.NegativePrompt
+	}
+	switch o.SeedPolicy {
+	case SeedFixed:
+		p["seed"] = o.Seed
+	case SeedWord:
+		h := fnv.New32a()
+		h.Write([]byte(strings.ToLower(strings.TrimSpace(word))))
+		p["seed"] = int64(h.Sum32())
+	}
+	return p
+}
+
+func or(s, def string) string {
+	if s != "" {
+		return s
+	}
+	return def
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/cost"
+)
+
+// Defaults used for zero Anthropic fields.
+const (
+	DefaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	DefaultAnthropicModel     = "claude-3-5-haiku-latest"
+	DefaultAnthropicMaxTokens = 2048
+	anthropicVersion          = "2023-06-01"
+)
+
+// Anthropic talks to the Anthropic Messages API. Structured answers are
+// requested by forcing a tool whose input schema is the JSON schema.
+type Anthropic struct {
+	APIKey string
+	// BaseURL defaults to DefaultAnthropicBaseURL.
+	BaseURL string
+	// Model defaults to DefaultAnthropicModel.
+	Model string
+	// Temperature defaults to DefaultTemperature when nil.
+	Temperature *float64
+	// MaxTokens defaults to DefaultAnthropicMaxTokens; the API requires a limit.
+	MaxTokens int
+}
+
+func (a Anthropic) Complete(ctx context.Context, messages []Message, schema *Schema) (string, error) {
+	baseURL := a.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultAnthropicBaseURL
+	}
+	model := a.Model
+	if model == "" {
+		model = DefaultAnthropicModel
+	}
+	maxTokens := a.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = DefaultAnthropicMaxTokens
+	}
+
+	// System messages go into the top-level system prompt.
+	var system []string
+	var turns []Message
+	for _, m := range messages {
+		if m.Role == "system" {
+			system = append(system, m.Content)
+		} else {
+			turns = append(turns, m)
+		}
+	}
+	payload := map[string]interface{}{
+		"model":       model,
+		"messages":    turns,
+		"max_tokens":  maxTokens,
+		"temperature": temperature(a.Temperature),
+	}
+	if len(system) > 0 {
+		payload["system"] = strings.Join(system, "\n\n")
+	}
+	if schema != nil {
+		payload["tools"] = []map[string]interface{}{{
+			"name":         schema.Name,
+			"description":  "Return the answer as " + schema.Name + ".",
+			"input_schema": schema.Schema,
+		}}
+		payload["tool_choice"] = map[string]string{"type": "tool", "name": schema.Name}
+	}
+	headers := map[string]string{
+		"x-api-key":         a.APIKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var resp struct {
+		Content []struct {
+			Type  string          `json:"type"`
+			Text  string          `json:"text"`
+			Input json.RawMessage `json:"input"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := postJSON(ctx, "Anthropic", strings.TrimSuffix(baseURL, "/")+"/messages", headers, payload, &resp); err != nil {
+		return "", err
+	}
+	cost.Record(ctx, cost.Usage{
+		Provider:     "anthropic",
+		Model:        model,
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+	})
+	if resp.StopReason == "refusal" {
+		return "", fmt.Errorf("%w: the model refused to answer", apierror.ErrContentRejected)
+	}
+	var text strings.Builder
+	for _, c := range resp.Content {
+		switch c.Type {
+		case "tool_use":
+			if schema != nil {
+				return string(c.Input), nil
+			}
+		case "text":
+			text.WriteString(c.Text)
+		}
+	}
+	if schema != nil || text.Len() == 0 {
+		return "", fmt.Errorf("%w: no answer returned from API", apierror.ErrBadResponse)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/cost"
+)
+
+// Defaults used for zero Gemini fields.
+const (
+	DefaultGeminiBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	DefaultGeminiModel   = "gemini-2.0-flash"
+)
+
+// Gemini talks to the Gemini generateContent API.
+type Gemini struct {
+	APIKey string
+	// BaseURL defaults to DefaultGeminiBaseURL.
+	BaseURL string
+	// Model defaults to DefaultGeminiModel.
+	Model string
+	// Temperature defaults to DefaultTemperature when nil.
+	Temperature *float64
+	// MaxTokens limits the length of the answer. Zero leaves it to the server.
+	MaxTokens int
+}
+
+type geminiPart struct {
+	Text string `json:"text"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
+	Parts []geminiPart `json:"parts"`
+}
+
+func (g Gemini) Complete(ctx context.Context, messages []Message, schema *Schema) (string, error) {
+	baseURL := g.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultGeminiBaseURL
+	}
+	model := g.Model
+	if model == "" {
+		model = DefaultGeminiModel
+	}
+
+	var system []geminiPart
+	var contents []geminiContent
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			system = append(system, geminiPart{Text: m.Content})
+		case "assistant":
+			contents = append(contents, geminiContent{Role: "model", Parts: []geminiPart{{Text: m.Content}}})
+		default:
+			contents = append(contents, geminiContent{Role: "user", Parts: []geminiPart{{Text: m.Content}}})
+		}
+	}
+	config := map[string]interface{}{"temperature": temperature(g.Temperature)}
+	if g.MaxTokens > 0 {
+		config["maxOutputTokens"] = g.MaxTokens
+	}
+	if schema != nil {
+		config["responseMimeType"] = "application/json"
+		config["responseJsonSchema"] = schema.Schema
+	}
+	payload := map[string]interface{}{
+		"contents":         contents,
+		"generationConfig": config,
+	}
+	if len(system) > 0 {
+		payload["systemInstruction"] = geminiContent{Parts: system}
+	}
+
+	var resp struct {
+		Candidates []struct {
+			Content      geminiContent `json:"content"`
+			FinishReason string        `json:"finishReason"`
+		} `json:"candidates"`
+		PromptFeedback struct {
+			BlockReason string `json:"blockReason"`
+		} `json:"promptFeedback"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+		} `json:"usageMetadata"`
+	}
+	url := strings.TrimSuffix(baseURL, "/") + "/models/" + model + ":generateContent"
+	if err := postJSON(ctx, "Gemini", url, map[string]string{"x-goog-api-key": g.APIKey}, payload, &resp); err != nil {
+		return "", err
+	}
+	cost.Record(ctx, cost.Usage{
+		Provider:     "gemini",
+		Model:        model,
+		InputTokens:  resp.UsageMetadata.PromptTokenCount,
+		OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+	})
+	if reason := resp.PromptFeedback.BlockReason; reason != "" {
+		return "", fmt.Errorf("%w: prompt blocked (%s)", apierror.ErrContentRejected, reason)
+	}
+	if len(resp.Candidates) == 0 {
+		return "", fmt.Errorf("%w: no candidates returned from API", apierror.ErrBadResponse)
+	}
+	c := resp.Candidates[0]
+	if c.FinishReason == "SAFETY" || c.FinishReason == "PROHIBITED_CONTENT" {
+		return "", fmt.Errorf("%w: answer blocked (%s)", apierror.ErrContentRejected, c.FinishReason)
+	}
+	var text strings.Builder
+	for _, p := range c.Content.Parts {
+		text.WriteString(p.Text)
+	}
+	return text.String(), nil
+}
@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"vokabelvision/apierror"
+)
+
+// Message is one message of a conversation. Role is "system", "user" or "assistant".
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Schema is a JSON schema the answer must follow.
+type Schema struct {
+	// Name identifies the schema, such as "vocab".
+	Name   string
+	Schema interface{}
+}
+
+// Client completes a conversation with a language model. When schema is
+// not nil, the answer is a JSON document following it.
+type Client interface {
+	Complete(ctx context.Context, messages []Message, schema *Schema) (string, error)
+}
+
+// Fallback tries each client in order until one answers.
+type Fallback []Client
+
+func (f Fallback) Complete(ctx context.Context, messages []Message, schema *Schema) (string, error) {
+	var errs []error
+	for i, c := range f {
+		content, err := c.Complete(ctx, messages, schema)
+		if err == nil {
+			return content, nil
+		}
+		if ctx.Err() != nil {
+			return "", err
+		}
+		errs = append(errs, err)
+		if i < len(f)-1 {
+			log.Printf("Language model %d failed, trying the next one: %v", i+1, err)
+		}
+	}
+	return "", errors.Join(errs...)
+}
+
+// StripCodeFence removes a Markdown code fence around s, which some
+// OpenAI-compatible servers add even when asked for plain JSON.
+func StripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.Index(s, "\n"); i >= 0 {
+		s = s[i+1:] // drop the language tag, such as "json"
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// postJSON sends payload to url with headers and decodes the JSON answer into result.
+func postJSON(ctx context.Context, provider, url string, headers map[string]string, payload, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return apierror.FromStatus(provider, resp.StatusCode, bodyBytes)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("%w: error decoding %s response: %v", apierror.ErrBadResponse, provider, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/cost"
+)
+
+// Defaults used for zero OpenAI fields.
+const (
+	DefaultOpenAIBaseURL = "https://api.openai.com/v1"
+	DefaultOpenAIModel   = "gpt-4o-mini"
+	DefaultTemperature   = 0.7
+)
+
+// Response formats for OpenAI.ResponseFormat.
+const (
+	FormatJSONSchema = "json_schema"
+	FormatJSONObject = "json_object"
+	FormatText       = "text"
+)
+
+// OpenAI talks to the chat completions endpoint of OpenAI or of any
+// OpenAI-compatible server, such as Ollama or llama.cpp, set by BaseURL.
+type OpenAI struct {
+	APIKey string
+	// BaseURL is the API root, such as "http://localhost:11434/v1". Defaults to DefaultOpenAIBaseURL.
+	BaseURL string
+	// Model defaults to DefaultOpenAIModel.
+	Model string
+	// Temperature defaults to DefaultTemperature when nil.
+	Temperature *float64
+	// MaxTokens limits the length of the answer. Zero leaves it to the server.
+	MaxTokens int
+	// Organization is sent as the OpenAI-Organization header when set.
+	Organization string
+	// ResponseFormat is FormatJSONSchema (the default), FormatJSONObject, or
+	// FormatText for servers without structured output support.
+	ResponseFormat string
+}
+
+func (o OpenAI) Complete(ctx context.Context, messages []Message, schema *Schema) (string, error) {
+	baseURL := o.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAIBaseURL
+	}
+	model := o.Model
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	payload := map[string]interface{}{
+		"model":       model,
+		"messages":    messages,
+		"temperature": temperature(o.Temperature),
+	}
+	if o.MaxTokens > 0 {
+		payload["max_tokens"] = o.MaxTokens
+	}
+	if schema != nil {
+		switch o.ResponseFormat {
+		case "", FormatJSONSchema:
+			// Structured outputs need a model that supports json_schema response formats.
+			payload["response_format"] = map[string]interface{}{
+				"type": "json_schema",
+				"json_schema": map[string]interface{}{
+					"name":   schema.Name,
+					"strict": true,
+					"schema": schema.Schema,
+				},
+			}
+		case FormatJSONObject:
+			payload["response_format"] = map[string]string{"type": "json_object"}
+		case FormatText:
+		default:
+			return "", fmt.Errorf("unknown response format %q", o.ResponseFormat)
+		}
+	}
+
+	headers := map[string]string{}
+	// Local servers often need no key.
+	if o.APIKey != "" {
+		headers["Authorization"] = "Bearer " + o.APIKey
+	}
+	if o.Organization != "" {
+		headers["OpenAI-Organization"] = o.Organization
+	}
+
+	var chatResp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+				Refusal string `json:"refusal"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := postJSON(ctx, "OpenAI", strings.TrimSuffix(baseURL, "/")+"/chat/completions", headers, payload, &chatResp); err != nil {
+		return "", err
+	}
+	cost.Record(ctx, cost.Usage{
+		Provider:     "openai",
+		Model:        model,
+		InputTokens:  chatResp.Usage.PromptTokens,
+		OutputTokens: chatResp.Usage.CompletionTokens,
+	})
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices returned from API", apierror.ErrBadResponse)
+	}
+	if refusal := chatResp.Choices[0].Message.Refusal; refusal != "" {
+		return "", fmt.Errorf("%w: %s", apierror.ErrContentRejected, refusal)
+	}
+	return chatResp.Choices[0].Message.Content, nil
+}
+
+func temperature(t *float64) float64 {
+	if t != nil {
+		return *t
+	}
+	return DefaultTemperature
+}
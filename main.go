@@ -1,135 +1,163 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"vokabelvision/chatgpt"
-	"vokabelvision/cloudinary"
+	"vokabelvision/admin"
 	"vokabelvision/config"
-	"vokabelvision/elevenlabs"
-	"vokabelvision/instagram"
-	"vokabelvision/leonardo"
-	"vokabelvision/video"
-
-	"github.com/robfig/cron/v3"
+	"vokabelvision/pipeline"
+	"vokabelvision/scheduler"
 )
 
+// configPath is the location of the configuration file.
+const configPath = "config/config.json"
+
 func main() {
 
 	// Define the --once flag. It defaults to false.
 	once := flag.Bool("once", false, "Run the task once instead of scheduling it")
+	dryRun := flag.Bool("dry-run", false, "Render the reel into the preview folder without uploading or publishing it")
 
 	// Parse command-line flags.
 	flag.Parse()
 
+	// Cancel in-flight work on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Handle subcommands.
+	switch flag.Arg(0) {
+	case "resume":
+		if flag.NArg() != 2 {
+			log.Fatalf("Usage: vokabelvision resume <run-id>")
+		}
+		if err := ResumeRun(ctx, flag.Arg(1), *dryRun); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	case "queue":
+		if err := QueueCommand(ctx, flag.Args()[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	case "costs":
+		if err := CostsCommand(flag.Args()[1:]); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	case "":
+	default:
+		log.Fatalf("Unknown command %q", flag.Arg(0))
+	}
+
 	// Check if the --once flag is provided.
 	if *once {
-		GenerateAndPost()
-	} else {
-		// Load the Berlin location.
-		berlin, err := time.LoadLocation("Europe/Berlin")
-		if err != nil {
-			log.Fatalf("Failed to load Berlin timezone: %v", err)
+		if err := GenerateAndPost(ctx, *dryRun, config.Profile{}); err != nil {
+			log.Fatalf("%v", err)
 		}
+		return
+	}
 
-		// Create a new cron scheduler that runs in the Berlin timezone.
-		c := cron.New(cron.WithLocation(berlin))
-
-		// Schedule the job to run at 6 AM and 6 PM every day.
-		// Cron spec (minute hour day month day-of-week): "0 6,18 * * *"
-		_, err = c.AddFunc("0 7,13,19 * * *", func() {
-			GenerateAndPost()
-		})
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// Catch template mistakes now rather than at the first scheduled slot.
+	if err := pipeline.CheckTemplates(cfg); err != nil {
+		log.Fatalf("%v", err)
+	}
 
-		if err != nil {
+	// Create a scheduler with one entry per configured slot.
+	// A failed run is logged and recorded; the scheduler keeps running.
+	s := scheduler.New(ctx)
+	for _, entry := range cfg.Schedules {
+		var jitter time.Duration
+		if entry.Jitter != "" {
+			if jitter, err = time.ParseDuration(entry.Jitter); err != nil {
+				log.Fatalf("Invalid jitter %q for schedule %q: %v", entry.Jitter, entry.Cron, err)
+			}
+		}
+		profile := entry.Profile
+		if _, err := s.Add(entry.Cron, entry.Timezone, jitter, func(ctx context.Context) error {
+			return GenerateAndPost(ctx, *dryRun, profile)
+		}); err != nil {
 			log.Fatalf("Failed to add cron job: %v", err)
 		}
+	}
 
-		// Start the cron scheduler.
-		c.Start()
-		log.Println("Scheduler started. Waiting for scheduled tasks...")
+	// Start the scheduler.
+	s.Start()
+	log.Println("Scheduler started. Waiting for scheduled tasks...")
+	for _, e := range s.Entries() {
+		log.Printf("Next run of %q (%s): %s", e.Spec, e.Timezone, e.Next.Format(time.RFC1123))
+	}
 
-		// Block forever to keep the application running.
-		select {}
+	// Serve the admin API and dashboard if configured.
+	if cfg.AdminAddr != "" {
+		srv := &admin.Server{Scheduler: s, RunsDir: cfg.RunsDir, Token: cfg.AdminToken}
+		go func() {
+			if err := srv.ListenAndServe(ctx, cfg.AdminAddr); err != nil {
+				log.Printf("Admin server failed: %v", err)
+			}
+		}()
 	}
+
+	// Run until a signal arrives, then wait for the cancelled job to wind down.
+	<-ctx.Done()
+	log.Println("Shutting down scheduler...")
+	<-s.Stop().Done()
 }
 
-func GenerateAndPost() {
-	// Load configuration.
-	cfg, err := config.LoadConfig("config/config.json")
+// GenerateAndPost runs the full pipeline once for the given content profile.
+// With dryRun set it stops after rendering and writes a preview instead of publishing.
+func GenerateAndPost(ctx context.Context, dryRun bool, profile config.Profile) error {
+	cfg, err := config.LoadConfig(configPath)
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fmt.Errorf("failed to load config: %w", err)
 	}
-	// Step 1: Get vocabulary word from ChatGPT.
-	postedVocabFilePath := "postedvocabs.json"
-	vocab, err := chatgpt.GetVocab(cfg.ChatGPTAPIKey, postedVocabFilePath)
+	runner, err := newRunner(cfg, profile)
 	if err != nil {
-		log.Fatalf("Error getting vocab: %v", err)
+		return err
 	}
-	fmt.Printf("Got vocab: %+v\n", vocab)
-	// // // Step 2: Generate Leonardo.ai prompt.
-	prompt := leonardo.GeneratePrompt(vocab.English, vocab.German, vocab.Sentence)
-	fmt.Println("Generated Leonardo prompt:", prompt)
+	runner.DryRun = dryRun
+	return runner.Run(ctx)
+}
 
-	// Step 3: Get image from Leonardo.ai.
-	imagePath, err := leonardo.GetImage(cfg.LeonardoAPIKey, prompt)
-	if err != nil {
-		log.Fatalf("Error getting image: %v", err)
-	}
-	fmt.Println("Image saved at:", imagePath)
-	// os.Exit(1)
-	// Step 4: Get audio from ElevenLabs.
-	audioPath, err := elevenlabs.GetAudio(cfg.ElevenLabsAPIKey, vocab.German, vocab.Sentence, cfg.ElevenLabsVoiceID)
+// ResumeRun continues a previous run from its first unfinished stage.
+func ResumeRun(ctx context.Context, id string, dryRun bool) error {
+	runner, err := loadRunnerForRun(id)
 	if err != nil {
-		log.Fatalf("Error getting audio: %v", err)
+		return err
 	}
-	fmt.Println("Audio saved at:", audioPath)
-
-	// Step 5: Generate video reel.
-	// imagePath := "vocab_image.jpg"
-	// audioPath := "vocab_audio.mp3"
-	outputVideoPath := "vocab_reel.mp4"
+	runner.DryRun = dryRun
+	return runner.Resume(ctx, id)
+}
 
-	if err := video.GenerateVideo(imagePath, audioPath, outputVideoPath); err != nil {
-		log.Fatalf("Error generating video: %v", err)
+// loadRunnerForRun builds the pipeline for the content profile of an existing run.
+func loadRunnerForRun(id string) (*pipeline.Runner, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
-	fmt.Println("Video generated at:", outputVideoPath)
-
-	videoURL, publicID := cloudinary.UploadVideo(cfg.CloudinaryURL, outputVideoPath)
-
-	// // Step 6: Upload video to Instagram.
-	captionWithTags := fmt.Sprintf("%s #love #instagood #instagram #art #happy #travel #repost #german #germanlanguage", vocab.Caption)
-	if err := instagram.PublishVideo(cfg.InstagramUserID, cfg.InstagramAccessToken, videoURL, captionWithTags); err != nil {
-		log.Fatalf("Error uploading video: %v", err)
+	m, err := pipeline.LoadManifest(cfg.RunsDir, id)
+	if err != nil {
+		return nil, err
 	}
-	fmt.Println("Reel uploaded successfully!")
-	chatgpt.UpdatePostedVocabs(postedVocabFilePath, vocab.English)
-	cloudinary.DeleteVideo(cfg.CloudinaryURL, publicID)
-	DeleteFileIfExists(outputVideoPath)
-	DeleteFileIfExists("vocab_audio.mp3")
-	DeleteFileIfExists("vocab_image.jpg")
+	return newRunner(cfg, m.Profile)
 }
 
-// DeleteFileIfExists deletes the specified file if it exists.
-func DeleteFileIfExists(filename string) error {
-	// Check if the file exists.
-	if _, err := os.Stat(filename); err == nil {
-		// File exists, attempt deletion.
-		err = os.Remove(filename)
-		if err != nil {
-			return fmt.Errorf("failed to delete file: %v", err)
-		}
-		fmt.Printf("File %s deleted successfully.\n", filename)
-	} else if os.IsNotExist(err) {
-		// File does not exist.
-		fmt.Printf("File %s does not exist.\n", filename)
-	} else {
-		// Some other error occurred.
-		return fmt.Errorf("error checking file: %v", err)
+// newRunner builds the pipeline for a content profile.
+func newRunner(cfg config.Config, profile config.Profile) (*pipeline.Runner, error) {
+	runner, err := pipeline.New(cfg, profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up pipeline: %w", err)
 	}
-	return nil
+	return runner, nil
 }
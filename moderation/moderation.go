@@ -0,0 +1,54 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Decision is the outcome of checking the text of one vocab.
+type Decision struct {
+	// Word is the vocab the decision is about.
+	Word    string `json:"word"`
+	Flagged bool   `json:"flagged"`
+	// Reasons lists the flagged categories and blocklist matches.
+	Reasons   []string  `json:"reasons,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Blocklist flags texts that contain blocked words. Matching ignores case
+// and only matches whole words.
+type Blocklist struct {
+	rules []rule
+}
+
+type rule struct {
+	reason string
+	re     *regexp.Regexp
+}
+
+// NewBlocklist builds a blocklist from words, which may be phrases.
+func NewBlocklist(words []string) *Blocklist {
+	var b Blocklist
+	for _, p := range words {
+		if p = strings.TrimSpace(p); p != "" {
+			b.rules = append(b.rules, rule{
+				reason: "blocked word: " + p,
+				re:     regexp.MustCompile(`(?i)(^|[^\pL\pN])` + regexp.QuoteMeta(p) + `($|[^\pL\pN])`),
+			})
+		}
+	}
+	return &b
+}
+
+// Check returns the reasons texts are blocked, or nothing if they are not.
+func (b *Blocklist) Check(texts []string) []string {
+	all := strings.Join(texts, "\n")
+	var reasons []string
+	for _, r := range b.rules {
+		if r.re.MatchString(all) {
+			reasons = append(reasons, r.reason)
+		}
+	}
+	return reasons
+}
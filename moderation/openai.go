@@ -0,0 +1,89 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"vokabelvision/apierror"
+)
+
+// Defaults for zero OpenAI fields.
+const (
+	DefaultBaseURL = "https://api.openai.com/v1"
+	DefaultModel   = "omni-moderation-latest"
+)
+
+// OpenAI checks texts with the OpenAI moderation endpoint.
+type OpenAI struct {
+	APIKey string
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL string
+	// Model defaults to DefaultModel.
+	Model string
+}
+
+// Check returns the categories the texts are flagged for, or nothing if
+// none is flagged.
+func (o OpenAI) Check(ctx context.Context, texts []string) ([]string, error) {
+	baseURL := o.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	model := o.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	body, err := json.Marshal(map[string]interface{}{"model": model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(baseURL, "/")+"/moderations", bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+o.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, apierror.FromStatus("OpenAI moderation", resp.StatusCode, bodyBytes)
+	}
+
+	var result struct {
+		Results []struct {
+			Flagged    bool            `json:"flagged"`
+			Categories map[string]bool `json:"categories"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: error decoding moderation response: %v", apierror.ErrBadResponse, err)
+	}
+	categories := map[string]bool{}
+	for _, r := range result.Results {
+		for c, flagged := range r.Categories {
+			if flagged {
+				categories[c] = true
+			}
+		}
+		if r.Flagged && len(r.Categories) == 0 {
+			categories["flagged"] = true
+		}
+	}
+	var reasons []string
+	for c := range categories {
+		reasons = append(reasons, c)
+	}
+	sort.Strings(reasons)
+	return reasons, nil
+}
@@ -0,0 +1,75 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"vokabelvision/apierror"
+	"vokabelvision/llm"
+)
+
+// Topics flags texts that touch on blocked topics. Unlike blocked words,
+// topics are not matched literally: a language model decides whether a text
+// is about one, so "religion" also catches "Kirche" or "beten".
+type Topics struct {
+	LLM    llm.Client
+	Topics []string
+}
+
+// topicsSchema is the JSON schema of the classifier's answer.
+var topicsSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"topics": map[string]interface{}{
+			"type":  "array",
+			"items": map[string]string{"type": "string"},
+		},
+	},
+	"required":             []string{"topics"},
+	"additionalProperties": false,
+}
+
+// Check returns a reason for every blocked topic the texts touch on, or
+// nothing if they touch on none.
+func (t Topics) Check(ctx context.Context, texts []string) ([]string, error) {
+	if len(t.Topics) == 0 {
+		return nil, nil
+	}
+	given, err := json.Marshal(texts)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := json.Marshal(t.Topics)
+	if err != nil {
+		return nil, err
+	}
+	prompt := "You screen the text of an Instagram reel for language learners before it is published. " +
+		"The texts, which may be in any language, are: " + string(given) + ". " +
+		"The blocked topics are: " + string(topics) + ". " +
+		"List in 'topics' every blocked topic, spelled exactly as given, that any of the texts is about, mentions or alludes to, " +
+		"in any language and including related words. Answer with an empty list if none is."
+
+	content, err := t.LLM.Complete(ctx, []llm.Message{{Role: "user", Content: prompt}}, &llm.Schema{Name: "topics", Schema: topicsSchema})
+	if err != nil {
+		return nil, err
+	}
+	var answer struct {
+		Topics []string `json:"topics"`
+	}
+	if err := json.Unmarshal([]byte(llm.StripCodeFence(content)), &answer); err != nil {
+		return nil, fmt.Errorf("%w: error parsing topics JSON: %v", apierror.ErrBadResponse, err)
+	}
+	// Only report configured topics, whatever else the model answers.
+	var reasons []string
+	for _, topic := range t.Topics {
+		for _, a := range answer.Topics {
+			if strings.EqualFold(strings.TrimSpace(a), topic) {
+				reasons = append(reasons, "blocked topic: "+topic)
+				break
+			}
+		}
+	}
+	return reasons, nil
+}
@@ -0,0 +1,33 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"vokabelvision/apierror"
+	"vokabelvision/jsonl"
+)
+
+const failuresFile = "failures.jsonl"
+
+// Failure is one entry of the failure log kept next to the run directories.
+type Failure struct {
+	Time  time.Time `json:"time"`
+	RunID string    `json:"run_id,omitempty"`
+	Kind  string    `json:"kind"`
+	Error string    `json:"error"`
+}
+
+// RecordFailure appends a failed run to the failure log in runsDir.
+func RecordFailure(runsDir, runID string, err error) error {
+	if mkErr := os.MkdirAll(runsDir, 0755); mkErr != nil {
+		return mkErr
+	}
+	return jsonl.Append(filepath.Join(runsDir, failuresFile), Failure{
+		Time:  time.Now(),
+		RunID: runID,
+		Kind:  apierror.Kind(err),
+		Error: err.Error(),
+	})
+}
@@ -0,0 +1,181 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/config"
+	"vokabelvision/imagecheck"
+	"vokabelvision/moderation"
+)
+
+// Stage names, in the order a run executes them.
+const (
+	StageVocab   = "vocab"
+	StageImage   = "image"
+	StageAudio   = "audio"
+	StageVideo   = "video"
+	StageUpload  = "upload"
+	StagePublish = "publish"
+	StageRecord  = "record"
+	StageCleanup = "cleanup"
+)
+
+// Stages lists every stage of a run in execution order.
+var Stages = []string{StageVocab, StageImage, StageAudio, StageVideo, StageUpload, StagePublish, StageRecord, StageCleanup}
+
+// Stage statuses.
+const (
+	StatusPending = "pending"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+const manifestFile = "manifest.json"
+
+// StageResult records the outcome of one stage of a run.
+type StageResult struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Manifest describes a single run and everything it has produced so far.
+// It is stored as manifest.json inside the run's directory.
+type Manifest struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	DryRun    bool      `json:"dry_run,omitempty"`
+	// Profile is the content profile the run was generated for.
+	Profile config.Profile `json:"profile"`
+	// Review is the approval state when the run goes through the review queue.
+	Review     string    `json:"review,omitempty"`
+	ReviewedAt time.Time `json:"reviewed_at,omitempty"`
+	// Candidates are the scored vocab candidates the vocab was picked from, best first.
+	Candidates []ScoredCandidate `json:"candidates,omitempty"`
+	// Moderation lists the moderation decision on every vocab checked, including dropped ones.
+	Moderation []moderation.Decision `json:"moderation,omitempty"`
+	// Verification is the verifier's verdict on the vocab, when verification is enabled.
+	Verification *chatgpt.Verdict `json:"verification,omitempty"`
+	// Cost is what the run's API calls have cost so far, by the configured prices.
+	Cost float64 `json:"cost,omitempty"`
+	// Images are the generated images with the results of their checks.
+	// The chosen one is ImagePath; the others stay in the run directory.
+	Images []imagecheck.Result `json:"images,omitempty"`
+
+	Vocab     chatgpt.Vocab `json:"vocab"`
+	Prompt    string        `json:"prompt,omitempty"`
+	ImagePath string        `json:"image_path,omitempty"`
+	AudioPath string        `json:"audio_path,omitempty"`
+	Voice     string        `json:"voice,omitempty"`
+	VideoPath string        `json:"video_path,omitempty"`
+	Caption   string        `json:"caption,omitempty"`
+	VideoURL  string        `json:"video_url,omitempty"`
+	PublicID  string        `json:"cloudinary_public_id,omitempty"`
+	MediaID   string        `json:"media_id,omitempty"`
+	Stages    []StageResult `json:"stages"`
+	dir       string
+}
+
+// NewManifest creates a fresh run directory under runsDir and its manifest.
+func NewManifest(runsDir string) (*Manifest, error) {
+	now := time.Now()
+	m := &Manifest{CreatedAt: now}
+	if err := os.MkdirAll(runsDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating runs directory: %v", err)
+	}
+	// Runs started within the same second get a numbered suffix.
+	base := now.Format("20060102-150405")
+	for n := 1; ; n++ {
+		m.ID = base
+		if n > 1 {
+			m.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		m.dir = filepath.Join(runsDir, m.ID)
+		err := os.Mkdir(m.dir, 0755)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("error creating run directory: %v", err)
+		}
+	}
+	for _, name := range Stages {
+		m.Stages = append(m.Stages, StageResult{Name: name, Status: StatusPending})
+	}
+	return m, m.Save()
+}
+
+// LoadManifest reads the manifest of the run with the given ID from runsDir.
+func LoadManifest(runsDir, id string) (*Manifest, error) {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+	dir := filepath.Join(runsDir, id)
+	data, err := os.ReadFile(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading manifest for run %s: %v", id, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing manifest for run %s: %v", id, err)
+	}
+	m.dir = dir
+	return &m, nil
+}
+
+// Dir returns the run's working directory.
+func (m *Manifest) Dir() string {
+	return m.dir
+}
+
+// Path returns the path of a file inside the run's working directory.
+func (m *Manifest) Path(name string) string {
+	return filepath.Join(m.dir, name)
+}
+
+// Save writes the manifest to disk, replacing the previous version atomically.
+func (m *Manifest) Save() error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.Path(manifestFile + ".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.Path(manifestFile))
+}
+
+// Stage returns the result entry for the named stage.
+func (m *Manifest) Stage(name string) *StageResult {
+	for i := range m.Stages {
+		if m.Stages[i].Name == name {
+			return &m.Stages[i]
+		}
+	}
+	m.Stages = append(m.Stages, StageResult{Name: name, Status: StatusPending})
+	return &m.Stages[len(m.Stages)-1]
+}
+
+// Done reports whether the named stage has completed.
+func (m *Manifest) Done(name string) bool {
+	return m.Stage(name).Status == StatusDone
+}
+
+// SetStatus updates the named stage and saves the manifest.
+func (m *Manifest) SetStatus(name, status string, stageErr error) error {
+	s := m.Stage(name)
+	s.Status = status
+	s.Error = ""
+	if stageErr != nil {
+		s.Error = stageErr.Error()
+	}
+	s.UpdatedAt = time.Now()
+	return m.Save()
+}
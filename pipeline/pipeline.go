@@ -0,0 +1,668 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"vokabelvision/backlog"
+	"vokabelvision/caption"
+	"vokabelvision/chatgpt"
+	"vokabelvision/config"
+	"vokabelvision/cost"
+	"vokabelvision/history"
+	"vokabelvision/imagecheck"
+	"vokabelvision/moderation"
+	"vokabelvision/prompts"
+)
+
+// VocabSource produces the vocabulary for a reel. exclude lists recently
+// posted words the source should avoid.
+type VocabSource interface {
+	GetVocab(ctx context.Context, exclude []string) (chatgpt.Vocab, error)
+}
+
+// CandidateSource is implemented by vocab sources that can offer several
+// vocabs in one call to pick the best from.
+type CandidateSource interface {
+	GetCandidates(ctx context.Context, exclude []string, n int) ([]chatgpt.Candidate, error)
+}
+
+// Verifier proofreads a vocab before any media is generated for it.
+type Verifier interface {
+	Verify(ctx context.Context, vocab chatgpt.Vocab) (chatgpt.Verdict, error)
+}
+
+// Moderator checks the public text of a vocab and its caption before any
+// media is generated for it.
+type Moderator interface {
+	Moderate(ctx context.Context, vocab chatgpt.Vocab, caption string) (moderation.Decision, error)
+}
+
+// ImageGenerator builds an image prompt for a vocab and renders one or more
+// images for it, named after imagePath with a number, such as image-1.jpg.
+type ImageGenerator interface {
+	Prompt(vocab chatgpt.Vocab) (string, error)
+	GetImages(ctx context.Context, vocab chatgpt.Vocab, prompt, imagePath string) ([]string, error)
+}
+
+// SpeechSynthesizer renders the pronunciation audio for a vocab to a file.
+// Voice identifies the voice it speaks with.
+type SpeechSynthesizer interface {
+	GetAudio(ctx context.Context, vocab chatgpt.Vocab, audioPath string) (string, error)
+	Voice() string
+}
+
+// VideoRenderer combines an image and an audio track into a video file,
+// drawing the overlay lines on top.
+type VideoRenderer interface {
+	GenerateVideo(ctx context.Context, imagePath, audioPath, outputVideoPath string, overlay []string) error
+}
+
+// MediaHost makes a local video publicly reachable by URL.
+type MediaHost interface {
+	Upload(ctx context.Context, videoPath string) (url, publicID string, err error)
+	Delete(ctx context.Context, publicID string) error
+}
+
+// Publisher posts a hosted video with its caption and returns the ID of the post.
+type Publisher interface {
+	Publish(ctx context.Context, videoURL, caption string) (mediaID string, err error)
+}
+
+// Runner wires one implementation of every stage together. Verifier and
+// Moderator are optional; when set, they check every vocab, and the
+// verifier corrects or rejects it while the moderator can only reject it.
+type Runner struct {
+	Vocab     VocabSource
+	Verifier  Verifier
+	Moderator Moderator
+	Image     ImageGenerator
+	Speech    SpeechSynthesizer
+	Video     VideoRenderer
+	Host      MediaHost
+	Publisher Publisher
+
+	// Candidates is how many vocabs to ask a CandidateSource for at once.
+	// The best is used and the rest go to Backlog for later slots.
+	Candidates int
+	Backlog    *backlog.Store
+
+	// Captions builds the caption of every reel.
+	Captions *caption.Builder
+
+	// ImageChecks are the local checks every generated image must pass; the
+	// best scoring image that does is used.
+	ImageChecks imagecheck.Options
+
+	// History records every posted reel and is used to avoid duplicates.
+	History *history.Store
+
+	// Ledger records the API usage of every stage, priced with Prices.
+	// Once the calendar month's costs reach MonthlyBudget, Run no longer
+	// starts new runs and verification is skipped. Zero means no budget.
+	Ledger        *cost.Ledger
+	Prices        cost.Prices
+	MonthlyBudget float64
+
+	// RunsDir is the directory that holds one working directory per run.
+	RunsDir string
+	// Timeout bounds a single run. Zero means no deadline.
+	Timeout time.Duration
+	// DryRun stops a run after the video is rendered and writes the reel and
+	// caption to PreviewDir instead of uploading and publishing them.
+	DryRun     bool
+	PreviewDir string
+	// RequireApproval sends new reels to the review queue; only approved
+	// reels are published.
+	RequireApproval bool
+	// Profile is the content profile new runs are generated for.
+	Profile config.Profile
+}
+
+// cleanupTimeout bounds the removal of remote assets after a run was cancelled.
+const cleanupTimeout = 30 * time.Second
+
+const (
+	// recentWords is how many recently posted words are passed to the vocab source to avoid.
+	recentWords = 50
+	// maxVocabAttempts bounds how often a duplicate vocab is regenerated.
+	maxVocabAttempts = 5
+	// legacyPostedFile is the JSON array of posted English words used before the history store.
+	legacyPostedFile = "postedvocabs.json"
+)
+
+// ErrDuplicateVocab is returned when the vocab source keeps producing words
+// that were already posted or are waiting in the review queue.
+var ErrDuplicateVocab = errors.New("vocab was already posted")
+
+// ErrRejectedVocab is returned when the verifier rejects every vocab the source produces.
+var ErrRejectedVocab = errors.New("vocab was rejected by the verifier")
+
+// ErrFlaggedVocab is returned when moderation flags every vocab the source produces.
+var ErrFlaggedVocab = errors.New("vocab was flagged by moderation")
+
+// ErrNoUsableImage is returned when every generated image fails the image checks.
+var ErrNoUsableImage = errors.New("no generated image passed the checks")
+
+// CheckTemplates parses the prompt and caption templates of cfg and
+// renders them for a sample vocab, so mistakes show up at startup.
+func CheckTemplates(cfg config.Config) error {
+	if _, err := prompts.Load(cfg.PromptsDir); err != nil {
+		return fmt.Errorf("invalid prompt templates in %s: %w", cfg.PromptsDir, err)
+	}
+	if _, err := caption.New(captionOptions(cfg)); err != nil {
+		return fmt.Errorf("invalid caption settings: %w", err)
+	}
+	return nil
+}
+
+// New builds a Runner for the given content profile using the providers selected in cfg.
+func New(cfg config.Config, profile config.Profile) (*Runner, error) {
+	r := Runner{
+		History:         history.Open(cfg.HistoryFile),
+		RunsDir:         cfg.RunsDir,
+		PreviewDir:      cfg.PreviewDir,
+		RequireApproval: cfg.RequireApproval,
+		Profile:         profile,
+		Candidates:      cfg.VocabCandidates,
+		Backlog:         backlog.Open(cfg.BacklogFile),
+		Ledger:          cost.OpenLedger(cfg.Costs.LedgerFile),
+		Prices:          cfg.Costs.Prices,
+		MonthlyBudget:   cfg.Costs.MonthlyBudget,
+	}
+	if n, err := r.History.ImportPostedVocabs(legacyPostedFile); err != nil {
+		return nil, fmt.Errorf("error importing %s: %w", legacyPostedFile, err)
+	} else if n > 0 {
+		fmt.Printf("Imported %d words from %s into %s\n", n, legacyPostedFile, cfg.HistoryFile)
+	}
+	var err error
+	if cfg.RunTimeout != "" {
+		if r.Timeout, err = time.ParseDuration(cfg.RunTimeout); err != nil {
+			return nil, fmt.Errorf("invalid run_timeout %q: %v", cfg.RunTimeout, err)
+		}
+	}
+	templates, err := prompts.Load(cfg.PromptsDir)
+	if err != nil {
+		return nil, err
+	}
+	if r.Captions, err = caption.New(captionOptions(cfg)); err != nil {
+		return nil, err
+	}
+	if r.Vocab, err = newVocabSource(cfg, profile, r.History, templates); err != nil {
+		return nil, err
+	}
+	if r.Verifier, err = newVerifier(cfg); err != nil {
+		return nil, err
+	}
+	if r.Moderator, err = newModerator(cfg); err != nil {
+		return nil, err
+	}
+	if r.Image, err = newImageGenerator(cfg, profile, templates); err != nil {
+		return nil, err
+	}
+	r.ImageChecks = imageChecks(imageOptions(cfg, profile))
+	if r.Speech, err = newSpeechSynthesizer(cfg, profile); err != nil {
+		return nil, err
+	}
+	if r.Video, err = newVideoRenderer(cfg); err != nil {
+		return nil, err
+	}
+	if r.Host, err = newMediaHost(cfg); err != nil {
+		return nil, err
+	}
+	if r.Publisher, err = newPublisher(cfg, profile); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Run starts a new run in its own directory, generates a reel and publishes it.
+// When approval is required, Run instead publishes the oldest approved reel
+// and leaves the newly generated one in the review queue. Once the monthly
+// budget is spent, no new run is started.
+// Failures are recorded in the failure log as well as returned.
+func (r *Runner) Run(ctx context.Context) error {
+	review := r.RequireApproval && !r.DryRun
+	var publishErr error
+	if review {
+		if publishErr = r.PublishApproved(ctx); publishErr != nil {
+			log.Printf("Failed to publish approved reel: %v", publishErr)
+		}
+	}
+	over, err := r.overBudget()
+	if err != nil {
+		r.recordFailure("", err)
+		return errors.Join(publishErr, err)
+	}
+	if over {
+		log.Printf("Monthly budget of %.2f reached; skipping this slot", r.MonthlyBudget)
+		return publishErr
+	}
+	m, err := NewManifest(r.RunsDir)
+	if err != nil {
+		r.recordFailure("", err)
+		return errors.Join(publishErr, err)
+	}
+	m.DryRun = r.DryRun
+	m.Profile = r.Profile
+	if review {
+		m.Review = ReviewPending
+	}
+	if err := m.Save(); err != nil {
+		return errors.Join(publishErr, err)
+	}
+	fmt.Println("Started run", m.ID)
+	if err := r.execute(ctx, m); err != nil {
+		r.recordFailure(m.ID, err)
+		return errors.Join(publishErr, err)
+	}
+	if review {
+		fmt.Println("Run", m.ID, "is waiting for review")
+	}
+	return publishErr
+}
+
+// Resume continues the run with the given ID from its first unfinished stage.
+func (r *Runner) Resume(ctx context.Context, id string) error {
+	m, err := LoadManifest(r.RunsDir, id)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Resuming run", m.ID)
+	if err := r.execute(ctx, m); err != nil {
+		r.recordFailure(m.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (r *Runner) recordFailure(runID string, err error) {
+	if recErr := RecordFailure(r.RunsDir, runID, err); recErr != nil {
+		log.Printf("Failed to record failure: %v", recErr)
+	}
+}
+
+// execute runs every stage of m that has not completed yet, saving the
+// manifest after each one so a failed run can be resumed later.
+// If ctx is cancelled or the run's deadline passes, an uploaded but
+// unpublished video is removed from the media host before returning.
+func (r *Runner) execute(ctx context.Context, m *Manifest) error {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	steps := map[string]func(context.Context, *Manifest) error{
+		StageVocab:   r.stageVocab,
+		StageImage:   r.stageImage,
+		StageAudio:   r.stageAudio,
+		StageVideo:   r.stageVideo,
+		StageUpload:  r.stageUpload,
+		StagePublish: r.stagePublish,
+		StageRecord:  r.stageRecord,
+		StageCleanup: r.stageCleanup,
+	}
+	for _, name := range Stages {
+		if name == StageUpload {
+			if r.DryRun {
+				return r.writePreview(m)
+			}
+			// Reels are not published without approval when it is required,
+			// nor when they were rendered by a dry run. Such runs that never
+			// went through review, such as ones created before approval was
+			// required, join the review queue.
+			if (r.RequireApproval || m.DryRun) && m.Review == "" {
+				if err := m.setReview(ReviewPending); err != nil {
+					return err
+				}
+				fmt.Println("Run", m.ID, "is waiting for review")
+			}
+			if m.Review != "" && m.Review != ReviewApproved {
+				return nil
+			}
+		}
+		if m.Done(name) {
+			continue
+		}
+		meter := &cost.Meter{RunID: m.ID, Stage: name, Prices: r.Prices, Ledger: r.Ledger}
+		err := steps[name](cost.WithMeter(ctx, meter), m)
+		m.Cost += meter.Total()
+		if err != nil {
+			if ctx.Err() != nil {
+				r.abandonUpload(m)
+			}
+			if saveErr := m.SetStatus(name, StatusFailed, err); saveErr != nil {
+				log.Printf("Failed to save manifest for run %s: %v", m.ID, saveErr)
+			}
+			return fmt.Errorf("run %s: %w", m.ID, err)
+		}
+		if err := m.SetStatus(name, StatusDone, nil); err != nil {
+			return fmt.Errorf("run %s: error saving manifest: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// overBudget reports whether the costs of the current calendar month have
+// reached the monthly budget.
+func (r *Runner) overBudget() (bool, error) {
+	if r.MonthlyBudget <= 0 || r.Ledger == nil {
+		return false, nil
+	}
+	spent, err := r.Ledger.Since(cost.MonthStart(time.Now()))
+	if err != nil {
+		return false, fmt.Errorf("error reading cost ledger: %w", err)
+	}
+	return spent >= r.MonthlyBudget, nil
+}
+
+// abandonUpload deletes a hosted video that was never published and resets
+// the upload stage, so resuming the run uploads it again.
+func (r *Runner) abandonUpload(m *Manifest) {
+	if m.PublicID == "" || m.Done(StagePublish) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if err := r.Host.Delete(ctx, m.PublicID); err != nil {
+		log.Printf("Failed to delete hosted video %s of cancelled run %s: %v", m.PublicID, m.ID, err)
+		return
+	}
+	fmt.Println("Deleted hosted video of cancelled run", m.ID)
+	m.VideoURL = ""
+	m.PublicID = ""
+	m.Stage(StageUpload).Status = StatusPending
+}
+
+// stageVocab gets a vocab that has never been posted before and is not
+// waiting in the review queue, regenerating it when the source returns such
+// a word, the verifier rejects it or moderation flags it. When the vocab of
+// a run is regenerated, its current vocab counts as taken too. Corrections
+// from the verifier are applied and checked for duplicates again.
+func (r *Runner) stageVocab(ctx context.Context, m *Manifest) error {
+	lang := chatgpt.Vocab{Request: vocabRequest(r.Profile)}.Language()
+	exclude, err := r.History.Recent(recentWords, lang)
+	if err != nil {
+		return fmt.Errorf("error reading history: %w", err)
+	}
+	taken, err := r.queuedVocabs(m)
+	if err != nil {
+		return fmt.Errorf("error reading runs: %w", err)
+	}
+	for _, v := range taken {
+		if v.Language() == lang {
+			exclude = append(exclude, v.Word, v.Translation)
+		}
+	}
+	m.Verification = nil
+	m.Candidates = nil
+	m.Moderation = nil
+	verifier := r.Verifier
+	if verifier != nil {
+		over, err := r.overBudget()
+		if err != nil {
+			return err
+		}
+		if over {
+			fmt.Println("Monthly budget reached; skipping verification")
+			verifier = nil
+		}
+	}
+	rejected, flagged := false, false
+	for attempt := 1; attempt <= maxVocabAttempts; attempt++ {
+		vocab, err := r.nextVocab(ctx, m, exclude)
+		if err != nil {
+			return fmt.Errorf("error getting vocab: %w", err)
+		}
+		fmt.Printf("Got vocab: %+v\n", vocab)
+		dup, err := r.isTaken(vocab, taken)
+		if err != nil {
+			return err
+		}
+		if dup {
+			fmt.Printf("%s = %s was already posted or queued; regenerating\n", vocab.Word, vocab.Translation)
+			exclude = append(exclude, vocab.Word, vocab.Translation)
+			continue
+		}
+		if verifier != nil {
+			verdict, err := verifier.Verify(ctx, vocab)
+			if err != nil {
+				return fmt.Errorf("error verifying vocab: %w", err)
+			}
+			m.Verification = &verdict
+			switch verdict.Verdict {
+			case chatgpt.VerdictReject:
+				fmt.Printf("Verifier rejected %s = %s: %s\n", vocab.Word, vocab.Translation, strings.Join(verdict.Problems, "; "))
+				exclude = append(exclude, vocab.Word, vocab.Translation)
+				rejected = true
+				continue
+			case chatgpt.VerdictCorrected:
+				fmt.Printf("Verifier corrected %s = %s: %s\n", vocab.Word, vocab.Translation, strings.Join(verdict.Problems, "; "))
+				exclude = append(exclude, vocab.Word, vocab.Translation)
+				vocab = *verdict.Corrected
+				// The correction may change the word or translation into one that is taken.
+				dup, err := r.isTaken(vocab, taken)
+				if err != nil {
+					return err
+				}
+				if dup {
+					fmt.Printf("Corrected vocab %s = %s was already posted or queued; regenerating\n", vocab.Word, vocab.Translation)
+					exclude = append(exclude, vocab.Word, vocab.Translation)
+					continue
+				}
+			}
+		}
+		caption, err := r.Captions.Build(vocab)
+		if err != nil {
+			return err
+		}
+		if r.Moderator != nil {
+			decision, err := r.Moderator.Moderate(ctx, vocab, caption)
+			if err != nil {
+				return fmt.Errorf("error moderating vocab: %w", err)
+			}
+			m.Moderation = append(m.Moderation, decision)
+			if decision.Flagged {
+				fmt.Printf("Moderation flagged %s = %s: %s\n", vocab.Word, vocab.Translation, strings.Join(decision.Reasons, "; "))
+				exclude = append(exclude, vocab.Word, vocab.Translation)
+				flagged = true
+				continue
+			}
+		}
+		m.Vocab = vocab
+		m.Caption = caption
+		return nil
+	}
+	if flagged {
+		return fmt.Errorf("%w after %d attempts", ErrFlaggedVocab, maxVocabAttempts)
+	}
+	if rejected {
+		return fmt.Errorf("%w after %d attempts", ErrRejectedVocab, maxVocabAttempts)
+	}
+	return fmt.Errorf("%w after %d attempts", ErrDuplicateVocab, maxVocabAttempts)
+}
+
+// isTaken reports whether vocab was posted before or names the same word
+// as one of taken.
+func (r *Runner) isTaken(vocab chatgpt.Vocab, taken []chatgpt.Vocab) (bool, error) {
+	for _, v := range taken {
+		if chatgpt.SameVocab(v, vocab) {
+			return true, nil
+		}
+	}
+	_, posted, err := r.History.Find(vocab)
+	if err != nil {
+		return false, fmt.Errorf("error reading history: %w", err)
+	}
+	return posted, nil
+}
+
+// nextVocab returns the next vocab to try: the best matching candidate left
+// in the backlog by an earlier run, or else the best of r.Candidates fresh
+// candidates, or a single vocab from a source that cannot offer candidates.
+func (r *Runner) nextVocab(ctx context.Context, m *Manifest, exclude []string) (chatgpt.Vocab, error) {
+	excluded := map[string]bool{}
+	for _, w := range exclude {
+		excluded[chatgpt.Normalize(w)] = true
+	}
+	want := vocabRequest(r.Profile)
+	// Dry runs only look at the backlog, so testing prompts does not use up
+	// or add candidates.
+	take := r.Backlog.Take
+	if r.DryRun {
+		take = r.Backlog.Peek
+	}
+	e, ok, err := take(func(e backlog.Entry) (bool, error) {
+		v := e.Candidate.Vocab
+		return v.Request == want && !excluded[chatgpt.Normalize(v.Word)] && !excluded[chatgpt.Normalize(v.Translation)], nil
+	}, func(e backlog.Entry) (bool, error) {
+		_, posted, err := r.History.Find(e.Candidate.Vocab)
+		return posted, err
+	})
+	if err != nil {
+		return chatgpt.Vocab{}, fmt.Errorf("error reading backlog: %w", err)
+	}
+	if ok {
+		fmt.Printf("Using %s = %s from the backlog\n", e.Candidate.Vocab.Word, e.Candidate.Vocab.Translation)
+		return e.Candidate.Vocab, nil
+	}
+
+	source, ok := r.Vocab.(CandidateSource)
+	if !ok || r.Candidates <= 1 {
+		return r.Vocab.GetVocab(ctx, exclude)
+	}
+	candidates, err := source.GetCandidates(ctx, exclude, r.Candidates)
+	if err != nil {
+		return chatgpt.Vocab{}, err
+	}
+	posted, err := r.History.All()
+	if err != nil {
+		return chatgpt.Vocab{}, fmt.Errorf("error reading history: %w", err)
+	}
+	scored := scoreCandidates(candidates, r.Profile.Level, posted)
+	// Posted candidates are sorted last, so the first one is only posted when
+	// all are; the duplicate check then rejects it and asks for new ones.
+	if scored[0].Score.Novelty > 0 {
+		scored[0].Chosen = true
+		fmt.Printf("Picked %s = %s from %d candidates (score %.2f)\n", scored[0].Word, scored[0].Translation, len(scored), scored[0].Score.Total)
+	}
+	m.Candidates = append(m.Candidates, scored...)
+	var rest []backlog.Entry
+	for _, s := range scored[1:] {
+		if s.Score.Novelty > 0 {
+			rest = append(rest, backlog.Entry{AddedAt: time.Now(), Candidate: s.candidate, Score: s.Score.Total})
+		}
+	}
+	if len(rest) > 0 && !r.DryRun {
+		if err := r.Backlog.Add(rest...); err != nil {
+			log.Printf("Failed to save %d candidates to the backlog: %v", len(rest), err)
+		}
+	}
+	return scored[0].candidate.Vocab, nil
+}
+
+func (r *Runner) stageImage(ctx context.Context, m *Manifest) error {
+	prompt, err := r.Image.Prompt(m.Vocab)
+	if err != nil {
+		return err
+	}
+	m.Prompt = prompt
+	fmt.Println("Generated image prompt:", m.Prompt)
+	paths, err := r.Image.GetImages(ctx, m.Vocab, m.Prompt, m.Path("image.jpg"))
+	if err != nil {
+		return fmt.Errorf("error getting image: %w", err)
+	}
+	m.Images = nil
+	m.ImagePath = ""
+	for _, path := range paths {
+		result, err := imagecheck.Check(path, r.ImageChecks)
+		if err != nil {
+			return fmt.Errorf("error checking image: %w", err)
+		}
+		if !result.Passed() {
+			fmt.Printf("Image %s failed the checks: %s\n", path, strings.Join(result.Problems, "; "))
+		}
+		m.Images = append(m.Images, result)
+	}
+	best := imagecheck.Best(m.Images)
+	if best < 0 {
+		return fmt.Errorf("%w (%d images)", ErrNoUsableImage, len(m.Images))
+	}
+	m.Images[best].Chosen = true
+	m.ImagePath = m.Images[best].Path
+	fmt.Printf("Picked image %d of %d (score %.2f): %s\n", best+1, len(m.Images), m.Images[best].Score, m.ImagePath)
+	return nil
+}
+
+func (r *Runner) stageAudio(ctx context.Context, m *Manifest) error {
+	audioPath, err := r.Speech.GetAudio(ctx, m.Vocab, m.Path("audio.mp3"))
+	if err != nil {
+		return fmt.Errorf("error getting audio: %w", err)
+	}
+	fmt.Println("Audio saved at:", audioPath)
+	m.AudioPath = audioPath
+	m.Voice = r.Speech.Voice()
+	return nil
+}
+
+func (r *Runner) stageVideo(ctx context.Context, m *Manifest) error {
+	videoPath := m.Path("reel.mp4")
+	// ffmpeg refuses to overwrite an existing file from a failed attempt.
+	if err := DeleteFileIfExists(videoPath); err != nil {
+		return err
+	}
+	if err := r.Video.GenerateVideo(ctx, m.ImagePath, m.AudioPath, videoPath, overlayLines(m.Vocab)); err != nil {
+		return fmt.Errorf("error generating video: %w", err)
+	}
+	fmt.Println("Video generated at:", videoPath)
+	m.VideoPath = videoPath
+	return nil
+}
+
+func (r *Runner) stageUpload(ctx context.Context, m *Manifest) error {
+	videoURL, publicID, err := r.Host.Upload(ctx, m.VideoPath)
+	if err != nil {
+		return fmt.Errorf("error uploading video: %w", err)
+	}
+	m.VideoURL = videoURL
+	m.PublicID = publicID
+	return nil
+}
+
+func (r *Runner) stagePublish(ctx context.Context, m *Manifest) error {
+	mediaID, err := r.Publisher.Publish(ctx, m.VideoURL, m.Caption)
+	if err != nil {
+		return fmt.Errorf("error publishing video: %w", err)
+	}
+	m.MediaID = mediaID
+	fmt.Println("Reel uploaded successfully!")
+	return nil
+}
+
+func (r *Runner) stageRecord(ctx context.Context, m *Manifest) error {
+	entry := history.Entry{
+		RunID:    m.ID,
+		PostedAt: time.Now(),
+		Account:  m.Profile.Account,
+		Vocab:    m.Vocab,
+		MediaID:  m.MediaID,
+		Prompt:   m.Prompt,
+		Voice:    m.Voice,
+		Cost:     m.Cost,
+	}
+	if err := r.History.Append(entry); err != nil {
+		return fmt.Errorf("error updating history: %w", err)
+	}
+	return nil
+}
+
+func (r *Runner) stageCleanup(ctx context.Context, m *Manifest) error {
+	if err := r.Host.Delete(ctx, m.PublicID); err != nil {
+		return fmt.Errorf("error deleting hosted video: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,632 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"vokabelvision/backlog"
+	"vokabelvision/caption"
+	"vokabelvision/chatgpt"
+	"vokabelvision/cost"
+	"vokabelvision/history"
+)
+
+// fakeVocab returns its vocabs in order, ignoring exclude, and repeats the
+// last one once they run out.
+type fakeVocab struct {
+	vocabs   []chatgpt.Vocab
+	calls    int
+	excludes [][]string
+}
+
+func (f *fakeVocab) GetVocab(ctx context.Context, exclude []string) (chatgpt.Vocab, error) {
+	f.excludes = append(f.excludes, exclude)
+	v := f.vocabs[min(f.calls, len(f.vocabs)-1)]
+	f.calls++
+	return v, nil
+}
+
+// fakeVerifier returns the verdict for a word, or "correct".
+type fakeVerifier map[string]chatgpt.Verdict
+
+func (f fakeVerifier) Verify(ctx context.Context, vocab chatgpt.Vocab) (chatgpt.Verdict, error) {
+	if v, ok := f[vocab.Word]; ok {
+		return v, nil
+	}
+	return chatgpt.Verdict{Verdict: chatgpt.VerdictCorrect}, nil
+}
+
+// fakeImage writes one PNG per entry of blank: a blank image where it is
+// true, which fails the checks, and a detailed one where it is false.
+type fakeImage struct {
+	blank []bool
+	calls int
+}
+
+func (f *fakeImage) Prompt(vocab chatgpt.Vocab) (string, error) {
+	return "a picture of " + vocab.Translation, nil
+}
+
+func (f *fakeImage) GetImages(ctx context.Context, vocab chatgpt.Vocab, prompt, imagePath string) ([]string, error) {
+	f.calls++
+	var paths []string
+	for i, blank := range f.blank {
+		img := image.NewGray(image.Rect(0, 0, 16, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				if !blank {
+					img.SetGray(x, y, color.Gray{Y: uint8(x*16 + y)})
+				}
+			}
+		}
+		path := strings.TrimSuffix(imagePath, ".jpg") + "-" + string(rune('1'+i)) + ".png"
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := png.Encode(file, img); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := file.Close(); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+type fakeSpeech struct{ calls int }
+
+func (f *fakeSpeech) GetAudio(ctx context.Context, vocab chatgpt.Vocab, audioPath string) (string, error) {
+	f.calls++
+	return audioPath, os.WriteFile(audioPath, []byte(vocab.Word), 0644)
+}
+
+func (f *fakeSpeech) Voice() string { return "voice" }
+
+// fakeVideo fails its first fail calls.
+type fakeVideo struct {
+	fail  int
+	calls int
+	image string
+}
+
+func (f *fakeVideo) GenerateVideo(ctx context.Context, imagePath, audioPath, outputVideoPath string, overlay []string) error {
+	f.calls++
+	if f.calls <= f.fail {
+		return errors.New("ffmpeg failed")
+	}
+	f.image = imagePath
+	return os.WriteFile(outputVideoPath, []byte("video"), 0644)
+}
+
+type fakeHost struct{ uploads, deletes int }
+
+func (f *fakeHost) Upload(ctx context.Context, videoPath string) (string, string, error) {
+	f.uploads++
+	return "https://example.com/" + filepath.Base(filepath.Dir(videoPath)), filepath.Base(filepath.Dir(videoPath)), nil
+}
+
+func (f *fakeHost) Delete(ctx context.Context, publicID string) error {
+	f.deletes++
+	return nil
+}
+
+// fakePublisher fails to publish captions that contain fail.
+type fakePublisher struct {
+	fail      string
+	published []string
+}
+
+func (f *fakePublisher) Publish(ctx context.Context, videoURL, caption string) (string, error) {
+	if f.fail != "" && strings.Contains(caption, f.fail) {
+		return "", errors.New("instagram is down")
+	}
+	f.published = append(f.published, caption)
+	return "media", nil
+}
+
+// fakes holds the fake stages of a test runner.
+type fakes struct {
+	vocab     *fakeVocab
+	image     *fakeImage
+	speech    *fakeSpeech
+	video     *fakeVideo
+	host      *fakeHost
+	publisher *fakePublisher
+}
+
+func noun(word, translation string) chatgpt.Vocab {
+	return chatgpt.Vocab{Word: word, Translation: translation, Caption: word + " = " + translation, Sentence: word + " ist hier.", PartOfSpeech: "noun"}
+}
+
+// newTestRunner returns a runner of fakes working in a temporary directory.
+func newTestRunner(t *testing.T, vocabs ...chatgpt.Vocab) (*Runner, *fakes) {
+	t.Helper()
+	dir := t.TempDir()
+	captions, err := caption.New(caption.Options{MaxHashtags: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakes{
+		vocab:     &fakeVocab{vocabs: vocabs},
+		image:     &fakeImage{blank: []bool{false}},
+		speech:    &fakeSpeech{},
+		video:     &fakeVideo{},
+		host:      &fakeHost{},
+		publisher: &fakePublisher{},
+	}
+	r := &Runner{
+		Vocab:      f.vocab,
+		Image:      f.image,
+		Speech:     f.speech,
+		Video:      f.video,
+		Host:       f.host,
+		Publisher:  f.publisher,
+		Backlog:    backlog.Open(filepath.Join(dir, "backlog.jsonl")),
+		Captions:   captions,
+		History:    history.Open(filepath.Join(dir, "history.jsonl")),
+		Ledger:     cost.OpenLedger(filepath.Join(dir, "costs.jsonl")),
+		RunsDir:    filepath.Join(dir, "runs"),
+		PreviewDir: filepath.Join(dir, "previews"),
+	}
+	return r, f
+}
+
+// onlyRun returns the manifest of the only run in r.RunsDir.
+func onlyRun(t *testing.T, r *Runner) *Manifest {
+	t.Helper()
+	runs, err := ListRuns(r.RunsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 {
+		t.Fatalf("got %d runs, want 1", len(runs))
+	}
+	return runs[0]
+}
+
+// statuses returns the status of every stage of m in order.
+func statuses(m *Manifest) string {
+	var s []string
+	for _, name := range Stages {
+		s = append(s, m.Stage(name).Status)
+	}
+	return strings.Join(s, " ")
+}
+
+const (
+	allDone   = "done done done done done done done done"
+	rendered  = "done done done done pending pending pending pending"
+	videoFail = "done done done failed pending pending pending pending"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name            string
+		dryRun          bool
+		requireApproval bool
+		videoFails      int
+		publishFails    bool
+		wantErr         bool
+		wantStages      string
+		wantReview      string
+		wantPosted      int
+		wantPreview     bool
+		wantUploads     int
+	}{
+		{name: "publishes", wantStages: allDone, wantPosted: 1, wantUploads: 1},
+		{name: "dry run stops before uploading", dryRun: true, wantStages: rendered, wantPreview: true},
+		{name: "dry run ignores approval", dryRun: true, requireApproval: true, wantStages: rendered, wantPreview: true},
+		{name: "approval leaves the reel in the queue", requireApproval: true, wantStages: rendered, wantReview: ReviewPending},
+		{name: "failed stage stops the run", videoFails: 1, wantErr: true, wantStages: videoFail},
+		{name: "failed publishing", publishFails: true, wantErr: true, wantStages: "done done done done done failed pending pending", wantUploads: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, noun("der Apfel", "apple"))
+			r.DryRun = tt.dryRun
+			r.RequireApproval = tt.requireApproval
+			f.video.fail = tt.videoFails
+			if tt.publishFails {
+				f.publisher.fail = "Apfel"
+			}
+			err := r.Run(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Run: %v, want error %v", err, tt.wantErr)
+			}
+			m := onlyRun(t, r)
+			if got := statuses(m); got != tt.wantStages {
+				t.Errorf("stages %q, want %q", got, tt.wantStages)
+			}
+			if m.Review != tt.wantReview {
+				t.Errorf("review %q, want %q", m.Review, tt.wantReview)
+			}
+			if m.DryRun != tt.dryRun {
+				t.Errorf("dry run %v, want %v", m.DryRun, tt.dryRun)
+			}
+			posted, err := r.History.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(posted) != tt.wantPosted {
+				t.Errorf("%d reels posted, want %d", len(posted), tt.wantPosted)
+			}
+			_, err = os.Stat(filepath.Join(r.PreviewDir, m.ID+".mp4"))
+			if (err == nil) != tt.wantPreview {
+				t.Errorf("preview written: %v, want %v", err == nil, tt.wantPreview)
+			}
+			if f.host.uploads != tt.wantUploads {
+				t.Errorf("uploaded %d videos, want %d", f.host.uploads, tt.wantUploads)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	tests := []struct {
+		name            string
+		dryRun          bool
+		resumeApproval  bool
+		videoFails      int
+		wantStages      string
+		wantReview      string
+		wantVideoCalls  int
+		wantVocabCalls  int
+		wantImageCalls  int
+		wantPostedAfter int
+	}{
+		{name: "continues from the failed stage", videoFails: 1, wantStages: allDone, wantVideoCalls: 2, wantVocabCalls: 1, wantImageCalls: 1, wantPostedAfter: 1},
+		{name: "dry run goes to review", dryRun: true, wantStages: rendered, wantReview: ReviewPending, wantVideoCalls: 1, wantVocabCalls: 1, wantImageCalls: 1},
+		{name: "unreviewed run goes to review once approval is required", videoFails: 1, resumeApproval: true, wantStages: rendered, wantReview: ReviewPending, wantVideoCalls: 2, wantVocabCalls: 1, wantImageCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, noun("der Apfel", "apple"))
+			r.DryRun = tt.dryRun
+			f.video.fail = tt.videoFails
+			r.Run(context.Background())
+			id := onlyRun(t, r).ID
+
+			r.DryRun = false
+			r.RequireApproval = tt.resumeApproval
+			if err := r.Resume(context.Background(), id); err != nil {
+				t.Fatalf("Resume: %v", err)
+			}
+			m := onlyRun(t, r)
+			if got := statuses(m); got != tt.wantStages {
+				t.Errorf("stages %q, want %q", got, tt.wantStages)
+			}
+			if m.Review != tt.wantReview {
+				t.Errorf("review %q, want %q", m.Review, tt.wantReview)
+			}
+			if f.vocab.calls != tt.wantVocabCalls || f.image.calls != tt.wantImageCalls || f.video.calls != tt.wantVideoCalls {
+				t.Errorf("vocab, image and video called %d, %d and %d times, want %d, %d and %d",
+					f.vocab.calls, f.image.calls, f.video.calls, tt.wantVocabCalls, tt.wantImageCalls, tt.wantVideoCalls)
+			}
+			posted, _ := r.History.All()
+			if len(posted) != tt.wantPostedAfter {
+				t.Errorf("%d reels posted, want %d", len(posted), tt.wantPostedAfter)
+			}
+		})
+	}
+}
+
+// queueRuns generates one reel per vocab into the review queue and returns their IDs.
+func queueRuns(t *testing.T, r *Runner, n int) []string {
+	t.Helper()
+	r.RequireApproval = true
+	var ids []string
+	for i := 0; i < n; i++ {
+		m, err := NewManifest(r.RunsDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Review = ReviewPending
+		if err := r.execute(context.Background(), m); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+func TestPublishApproved(t *testing.T) {
+	tests := []struct {
+		name    string
+		approve []int
+		fail    string
+		wantErr bool
+		// wantReviews is the review state of each run afterwards.
+		wantReviews   []string
+		wantPublished []string
+	}{
+		{name: "nothing approved", wantReviews: []string{ReviewPending, ReviewPending}},
+		{name: "oldest approved first", approve: []int{0, 1}, wantReviews: []string{ReviewApproved, ReviewApproved}, wantPublished: []string{"der Apfel"}},
+		{name: "only approved runs", approve: []int{1}, wantReviews: []string{ReviewPending, ReviewApproved}, wantPublished: []string{"die Birne"}},
+		{name: "a failing run leaves the queue and the next is published", approve: []int{0, 1}, fail: "Apfel", wantErr: true,
+			wantReviews: []string{ReviewFailed, ReviewApproved}, wantPublished: []string{"die Birne"}},
+		{name: "a failing run alone", approve: []int{0}, fail: "Apfel", wantErr: true, wantReviews: []string{ReviewFailed, ReviewPending}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, noun("der Apfel", "apple"), noun("die Birne", "pear"))
+			ids := queueRuns(t, r, 2)
+			for _, i := range tt.approve {
+				if err := Approve(r.RunsDir, ids[i]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			f.publisher.fail = tt.fail
+			if err := r.PublishApproved(context.Background()); (err != nil) != tt.wantErr {
+				t.Fatalf("PublishApproved: %v, want error %v", err, tt.wantErr)
+			}
+			for i, id := range ids {
+				m, err := LoadManifest(r.RunsDir, id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if m.Review != tt.wantReviews[i] {
+					t.Errorf("run %d: review %q, want %q", i, m.Review, tt.wantReviews[i])
+				}
+			}
+			if len(f.publisher.published) != len(tt.wantPublished) {
+				t.Fatalf("published %q, want %q", f.publisher.published, tt.wantPublished)
+			}
+			for i, word := range tt.wantPublished {
+				if !strings.Contains(f.publisher.published[i], word) {
+					t.Errorf("published %q, want %q", f.publisher.published[i], word)
+				}
+			}
+			queue, err := Queue(r.RunsDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, m := range queue {
+				if m.Review == ReviewFailed && m.Stage(StagePublish).Error == "" {
+					t.Errorf("run %s failed without an error", m.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestRegenerate(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage string
+		// vocabs are returned by the source in order.
+		vocabs         []chatgpt.Vocab
+		wantErr        bool
+		wantWord       string
+		wantVocabCalls int
+		wantImageCalls int
+		wantAudioCalls int
+	}{
+		{name: "vocab skips the current word", stage: StageVocab,
+			vocabs:   []chatgpt.Vocab{noun("der Apfel", "apple"), noun("der Apfel", "apple"), noun("die Birne", "pear")},
+			wantWord: "die Birne", wantVocabCalls: 3, wantImageCalls: 2, wantAudioCalls: 2},
+		{name: "image", stage: StageImage, vocabs: []chatgpt.Vocab{noun("der Apfel", "apple")},
+			wantWord: "der Apfel", wantVocabCalls: 1, wantImageCalls: 2, wantAudioCalls: 1},
+		{name: "audio", stage: StageAudio, vocabs: []chatgpt.Vocab{noun("der Apfel", "apple")},
+			wantWord: "der Apfel", wantVocabCalls: 1, wantImageCalls: 1, wantAudioCalls: 2},
+		{name: "unknown stage", stage: StageUpload, vocabs: []chatgpt.Vocab{noun("der Apfel", "apple")}, wantErr: true,
+			wantWord: "der Apfel", wantVocabCalls: 1, wantImageCalls: 1, wantAudioCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, tt.vocabs...)
+			id := queueRuns(t, r, 1)[0]
+			if err := Reject(r.RunsDir, id); err != nil {
+				t.Fatal(err)
+			}
+			if err := r.Regenerate(context.Background(), id, tt.stage); (err != nil) != tt.wantErr {
+				t.Fatalf("Regenerate: %v, want error %v", err, tt.wantErr)
+			}
+			m := onlyRun(t, r)
+			if m.Vocab.Word != tt.wantWord {
+				t.Errorf("word %q, want %q", m.Vocab.Word, tt.wantWord)
+			}
+			if !tt.wantErr && (m.Review != ReviewPending || statuses(m) != rendered || f.video.calls != 2) {
+				t.Errorf("review %q, stages %q and %d videos, want a re-rendered pending run", m.Review, statuses(m), f.video.calls)
+			}
+			if f.vocab.calls != tt.wantVocabCalls || f.image.calls != tt.wantImageCalls || f.speech.calls != tt.wantAudioCalls {
+				t.Errorf("vocab, image and audio called %d, %d and %d times, want %d, %d and %d",
+					f.vocab.calls, f.image.calls, f.speech.calls, tt.wantVocabCalls, tt.wantImageCalls, tt.wantAudioCalls)
+			}
+			if tt.stage == StageVocab {
+				if last := f.vocab.excludes[len(f.vocab.excludes)-1]; !slices.Contains(last, "der Apfel") {
+					t.Errorf("exclude %q does not contain the current word", last)
+				}
+			}
+		})
+	}
+}
+
+func TestRegenerateNotQueued(t *testing.T) {
+	r, _ := newTestRunner(t, noun("der Apfel", "apple"))
+	if err := r.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Regenerate(context.Background(), onlyRun(t, r).ID, StageImage); err == nil {
+		t.Error("regenerated a published run")
+	}
+}
+
+func TestChooseImage(t *testing.T) {
+	tests := []struct {
+		name string
+		// blank marks the generated images that fail the checks.
+		blank      []bool
+		wantRunErr bool
+		// wantChosen is the image used after the run, counting from 1, or 0.
+		wantChosen int
+		choose     int
+		wantErr    bool
+	}{
+		{name: "another image", blank: []bool{false, false, false}, wantChosen: 1, choose: 3},
+		{name: "an image that failed the checks", blank: []bool{false, true}, wantChosen: 1, choose: 2},
+		{name: "after every image failed", blank: []bool{true, true}, wantRunErr: true, choose: 1},
+		{name: "out of range", blank: []bool{false}, wantChosen: 1, choose: 2, wantErr: true},
+		{name: "zero", blank: []bool{false}, wantChosen: 1, choose: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, noun("der Apfel", "apple"))
+			r.RequireApproval = true
+			f.image.blank = tt.blank
+			err := r.Run(context.Background())
+			if (err != nil) != tt.wantRunErr {
+				t.Fatalf("Run: %v, want error %v", err, tt.wantRunErr)
+			}
+			if tt.wantRunErr && !errors.Is(err, ErrNoUsableImage) {
+				t.Errorf("Run: %v, want ErrNoUsableImage", err)
+			}
+			m := onlyRun(t, r)
+			if tt.wantChosen > 0 && !m.Images[tt.wantChosen-1].Chosen {
+				t.Errorf("image %d was not chosen: %+v", tt.wantChosen, m.Images)
+			}
+			err = r.ChooseImage(context.Background(), m.ID, tt.choose)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ChooseImage: %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			m = onlyRun(t, r)
+			want := m.Images[tt.choose-1].Path
+			if m.ImagePath != want || f.video.image != want {
+				t.Errorf("image %q rendered with %q, want %q", m.ImagePath, f.video.image, want)
+			}
+			for i, img := range m.Images {
+				if img.Chosen != (i == tt.choose-1) {
+					t.Errorf("image %d chosen: %v", i+1, img.Chosen)
+				}
+			}
+			if statuses(m) != rendered || m.Review != ReviewPending {
+				t.Errorf("stages %q and review %q, want a re-rendered pending run", statuses(m), m.Review)
+			}
+			if f.image.calls != 1 {
+				t.Errorf("generated images %d times, want 1", f.image.calls)
+			}
+		})
+	}
+}
+
+func TestStageVocab(t *testing.T) {
+	posted := noun("der Hund", "dog")
+	tests := []struct {
+		name     string
+		vocabs   []chatgpt.Vocab
+		verdicts fakeVerifier
+		// queued is generated into the review queue before the run.
+		queued   []chatgpt.Vocab
+		wantWord string
+		wantErr  error
+	}{
+		{name: "new word", vocabs: []chatgpt.Vocab{noun("die Katze", "cat")}, wantWord: "die Katze"},
+		{name: "posted word is regenerated", vocabs: []chatgpt.Vocab{noun("Hund", "a dog"), noun("die Katze", "cat")}, wantWord: "die Katze"},
+		{name: "queued word is regenerated", queued: []chatgpt.Vocab{noun("die Maus", "mouse")},
+			vocabs: []chatgpt.Vocab{noun("die Maus", "mouse"), noun("die Katze", "cat")}, wantWord: "die Katze"},
+		{name: "only posted words", vocabs: []chatgpt.Vocab{posted}, wantErr: ErrDuplicateVocab},
+		{name: "correction is applied", vocabs: []chatgpt.Vocab{noun("die Kaze", "cat")},
+			verdicts: fakeVerifier{"die Kaze": {Verdict: chatgpt.VerdictCorrected, Corrected: ptr(noun("die Katze", "cat"))}}, wantWord: "die Katze"},
+		{name: "correction into a posted word is regenerated", vocabs: []chatgpt.Vocab{noun("der Hunt", "dog"), noun("die Katze", "cat")},
+			verdicts: fakeVerifier{"der Hunt": {Verdict: chatgpt.VerdictCorrected, Corrected: ptr(noun("der Hund", "dog"))}}, wantWord: "die Katze"},
+		{name: "rejected word is regenerated", vocabs: []chatgpt.Vocab{noun("der Blub", "blub"), noun("die Katze", "cat")},
+			verdicts: fakeVerifier{"der Blub": {Verdict: chatgpt.VerdictReject}}, wantWord: "die Katze"},
+		{name: "only rejected words", vocabs: []chatgpt.Vocab{noun("der Blub", "blub")},
+			verdicts: fakeVerifier{"der Blub": {Verdict: chatgpt.VerdictReject}}, wantErr: ErrRejectedVocab},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, tt.queued...)
+			if err := r.History.Append(history.Entry{Vocab: posted}); err != nil {
+				t.Fatal(err)
+			}
+			if len(tt.queued) > 0 {
+				queueRuns(t, r, len(tt.queued))
+			}
+			f.vocab.vocabs, f.vocab.calls = tt.vocabs, 0
+			if tt.verdicts != nil {
+				r.Verifier = tt.verdicts
+			}
+			m, err := NewManifest(r.RunsDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = r.stageVocab(context.Background(), m)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("stageVocab: %v, want %v", err, tt.wantErr)
+			}
+			if m.Vocab.Word != tt.wantWord {
+				t.Errorf("word %q, want %q", m.Vocab.Word, tt.wantWord)
+			}
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   float64
+		spent    float64
+		approved bool
+		wantRuns int
+		// wantPublished is how many reels were published.
+		wantPublished int
+	}{
+		{name: "no budget", spent: 100, wantRuns: 1, wantPublished: 1},
+		{name: "under budget", budget: 10, spent: 5, wantRuns: 1, wantPublished: 1},
+		{name: "budget spent", budget: 10, spent: 10, wantRuns: 0},
+		{name: "approved reels are still published", budget: 10, spent: 10, approved: true, wantRuns: 1, wantPublished: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, f := newTestRunner(t, noun("der Apfel", "apple"), noun("die Birne", "pear"))
+			if tt.approved {
+				id := queueRuns(t, r, 1)[0]
+				if err := Approve(r.RunsDir, id); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := r.Ledger.Append(cost.Usage{Time: time.Now(), Provider: "leonardo", Cost: tt.spent}); err != nil {
+				t.Fatal(err)
+			}
+			r.MonthlyBudget = tt.budget
+			calls := f.vocab.calls
+			if err := r.Run(context.Background()); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			runs, err := ListRuns(r.RunsDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(runs) != tt.wantRuns {
+				t.Errorf("%d runs, want %d", len(runs), tt.wantRuns)
+			}
+			if tt.wantRuns == 0 && f.vocab.calls != calls {
+				t.Errorf("vocab source was called over budget")
+			}
+			if len(f.publisher.published) != tt.wantPublished {
+				t.Errorf("published %d reels, want %d", len(f.publisher.published), tt.wantPublished)
+			}
+			failures, err := os.ReadFile(filepath.Join(r.RunsDir, failuresFile))
+			if err == nil && len(failures) > 0 {
+				t.Errorf("failures recorded: %s", failures)
+			}
+		})
+	}
+}
+
+func ptr(v chatgpt.Vocab) *chatgpt.Vocab { return &v }
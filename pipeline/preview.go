@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// writePreview copies the rendered reel and its caption to the preview
+// directory as <run-id>.mp4 and <run-id>.txt.
+func (r *Runner) writePreview(m *Manifest) error {
+	if err := os.MkdirAll(r.PreviewDir, 0755); err != nil {
+		return fmt.Errorf("error creating preview directory: %w", err)
+	}
+	videoPath := filepath.Join(r.PreviewDir, m.ID+".mp4")
+	if err := copyFile(m.VideoPath, videoPath); err != nil {
+		return fmt.Errorf("error copying preview video: %w", err)
+	}
+	captionPath := filepath.Join(r.PreviewDir, m.ID+".txt")
+	if err := os.WriteFile(captionPath, []byte(m.Caption+"\n"), 0644); err != nil {
+		return fmt.Errorf("error writing preview caption: %w", err)
+	}
+	fmt.Println("Dry run: preview written to", videoPath, "and", captionPath)
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,447 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"vokabelvision/caption"
+	"vokabelvision/chatgpt"
+	"vokabelvision/cloudinary"
+	"vokabelvision/config"
+	"vokabelvision/deck"
+	"vokabelvision/elevenlabs"
+	"vokabelvision/history"
+	"vokabelvision/imagecheck"
+	"vokabelvision/instagram"
+	"vokabelvision/language"
+	"vokabelvision/leonardo"
+	"vokabelvision/llm"
+	"vokabelvision/moderation"
+	"vokabelvision/prompts"
+	"vokabelvision/video"
+)
+
+// ChatGPTVocabSource asks the configured language model for a vocab with
+// the prompt rendered from the vocab template.
+type ChatGPTVocabSource struct {
+	LLM     llm.Client
+	Request chatgpt.VocabRequest
+	Prompts *prompts.Templates
+	Profile config.Profile
+}
+
+func (s ChatGPTVocabSource) GetVocab(ctx context.Context, exclude []string) (chatgpt.Vocab, error) {
+	prompt, err := s.Prompts.Vocab(s.Profile, exclude)
+	if err != nil {
+		return chatgpt.Vocab{}, err
+	}
+	return chatgpt.GetVocab(ctx, s.LLM, s.Request, prompt)
+}
+
+func (s ChatGPTVocabSource) GetCandidates(ctx context.Context, exclude []string, n int) ([]chatgpt.Candidate, error) {
+	prompt, err := s.Prompts.Vocab(s.Profile, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return chatgpt.GetCandidates(ctx, s.LLM, s.Request, prompt, n)
+}
+
+// DeckVocabSource posts the entries of a curated deck in order, skipping
+// those already in the history. When Fill is set, the language model
+// completes the fields an entry leaves empty.
+type DeckVocabSource struct {
+	Deck    *deck.Deck
+	History *history.Store
+	Request chatgpt.VocabRequest
+	Fill    llm.Client
+}
+
+func (s DeckVocabSource) GetVocab(ctx context.Context, exclude []string) (chatgpt.Vocab, error) {
+	excluded := map[string]bool{}
+	for _, w := range exclude {
+		excluded[chatgpt.Normalize(w)] = true
+	}
+	card, err := s.Deck.Next(s.Request.Level, s.Request.Language, func(v chatgpt.Vocab) (bool, error) {
+		if excluded[chatgpt.Normalize(v.Word)] || excluded[chatgpt.Normalize(v.Translation)] {
+			return true, nil
+		}
+		_, posted, err := s.History.Find(v)
+		return posted, err
+	})
+	if err != nil {
+		return chatgpt.Vocab{}, err
+	}
+	level := card.Request.Level
+	card.Request = s.Request
+	card.Request.PartOfSpeech = card.PartOfSpeech
+	if level != "" {
+		card.Request.Level = level
+	}
+	if card.Gender == "" {
+		target := language.LookupOr(card.Request.Language, language.DefaultTarget)
+		card.Gender, _ = target.GenderOf(card.Article)
+	}
+	if card.Validate() == nil {
+		return card, nil
+	}
+	if s.Fill != nil {
+		return chatgpt.FillVocab(ctx, s.Fill, card)
+	}
+	if card.Caption == "" {
+		card.Caption = fmt.Sprintf("%s = %s", card.Word, card.Translation)
+	}
+	return card, nil
+}
+
+// ChatGPTVerifier proofreads vocabs with a second language model prompt.
+type ChatGPTVerifier struct {
+	LLM llm.Client
+}
+
+func (v ChatGPTVerifier) Verify(ctx context.Context, vocab chatgpt.Vocab) (chatgpt.Verdict, error) {
+	return chatgpt.VerifyVocab(ctx, v.LLM, vocab)
+}
+
+// ContentModerator checks vocabs against a blocklist and, when they are
+// set, the blocked topics and the OpenAI moderation endpoint.
+type ContentModerator struct {
+	Blocklist *moderation.Blocklist
+	Topics    *moderation.Topics
+	OpenAI    *moderation.OpenAI
+}
+
+func (c ContentModerator) Moderate(ctx context.Context, vocab chatgpt.Vocab, caption string) (moderation.Decision, error) {
+	texts := []string{vocab.Word, vocab.Translation, vocab.Sentence, vocab.SentenceTranslation, caption}
+	reasons := c.Blocklist.Check(texts)
+	if c.Topics != nil {
+		flagged, err := c.Topics.Check(ctx, texts)
+		if err != nil {
+			return moderation.Decision{}, err
+		}
+		reasons = append(reasons, flagged...)
+	}
+	if c.OpenAI != nil {
+		flagged, err := c.OpenAI.Check(ctx, texts)
+		if err != nil {
+			return moderation.Decision{}, err
+		}
+		reasons = append(reasons, flagged...)
+	}
+	return moderation.Decision{Word: vocab.Word, Flagged: len(reasons) > 0, Reasons: reasons, CheckedAt: time.Now()}, nil
+}
+
+// LeonardoImageGenerator renders images with Leonardo.ai from the image template.
+type LeonardoImageGenerator struct {
+	APIKey  string
+	Options leonardo.ImageOptions
+	Prompts *prompts.Templates
+	Profile config.Profile
+}
+
+func (g LeonardoImageGenerator) Prompt(vocab chatgpt.Vocab) (string, error) {
+	return g.Prompts.Image(g.Profile, vocab)
+}
+
+func (g LeonardoImageGenerator) GetImages(ctx context.Context, vocab chatgpt.Vocab, prompt, imagePath string) ([]string, error) {
+	return leonardo.GetImages(ctx, g.APIKey, prompt, vocab.Word, imagePath, g.Options)
+}
+
+// ElevenLabsSynthesizer renders pronunciation audio with ElevenLabs.
+type ElevenLabsSynthesizer struct {
+	APIKey  string
+	VoiceID string
+}
+
+func (s ElevenLabsSynthesizer) GetAudio(ctx context.Context, vocab chatgpt.Vocab, audioPath string) (string, error) {
+	return elevenlabs.GetAudio(ctx, s.APIKey, vocab.Word, vocab.Sentence, s.VoiceID, audioPath)
+}
+
+func (s ElevenLabsSynthesizer) Voice() string {
+	return s.VoiceID
+}
+
+// FFmpegRenderer renders videos with the local ffmpeg binary.
+type FFmpegRenderer struct {
+	FontFile string
+}
+
+func (r FFmpegRenderer) GenerateVideo(ctx context.Context, imagePath, audioPath, outputVideoPath string, overlay []string) error {
+	return video.GenerateVideo(ctx, imagePath, audioPath, outputVideoPath, video.Overlay{Lines: overlay, FontFile: r.FontFile})
+}
+
+// CloudinaryHost hosts videos on Cloudinary.
+type CloudinaryHost struct {
+	URL string
+}
+
+func (h CloudinaryHost) Upload(ctx context.Context, videoPath string) (string, string, error) {
+	return cloudinary.UploadVideo(ctx, h.URL, videoPath)
+}
+
+func (h CloudinaryHost) Delete(ctx context.Context, publicID string) error {
+	return cloudinary.DeleteVideo(ctx, h.URL, publicID)
+}
+
+// InstagramPublisher publishes reels through the Instagram Graph API.
+type InstagramPublisher struct {
+	UserID      string
+	AccessToken string
+}
+
+func (p InstagramPublisher) Publish(ctx context.Context, videoURL, caption string) (string, error) {
+	return instagram.PublishVideo(ctx, p.UserID, p.AccessToken, videoURL, caption)
+}
+
+// The constructors below map the provider names in config.Providers to
+// implementations. An empty name selects the default provider.
+
+func newVocabSource(cfg config.Config, profile config.Profile, hist *history.Store, p *prompts.Templates) (VocabSource, error) {
+	switch cfg.Providers.Vocab {
+	case "", "chatgpt":
+		client, err := newLLM(cfg.LLM, cfg.ChatGPTAPIKey)
+		if err != nil {
+			return nil, err
+		}
+		return ChatGPTVocabSource{
+			LLM:     client,
+			Request: vocabRequest(profile),
+			Prompts: p,
+			Profile: profile,
+		}, nil
+	case "deck":
+		if cfg.DeckFile == "" {
+			return nil, fmt.Errorf("the deck vocab provider needs deck_file")
+		}
+		d, err := deck.Open(cfg.DeckFile)
+		if err != nil {
+			return nil, err
+		}
+		source := DeckVocabSource{Deck: d, History: hist, Request: vocabRequest(profile)}
+		// Without a language model, incomplete entries are posted as they are.
+		if cfg.ChatGPTAPIKey != "" || cfg.LLM.APIKey != "" || cfg.LLM.BaseURL != "" {
+			if source.Fill, err = newLLM(cfg.LLM, cfg.ChatGPTAPIKey); err != nil {
+				return nil, err
+			}
+		}
+		return source, nil
+	}
+	return nil, fmt.Errorf("unknown vocab provider %q", cfg.Providers.Vocab)
+}
+
+// vocabRequest builds the vocab targeting of a content profile.
+func vocabRequest(profile config.Profile) chatgpt.VocabRequest {
+	return chatgpt.VocabRequest{
+		Level:          profile.Level,
+		Topic:          profile.Theme,
+		PartOfSpeech:   profile.PartOfSpeech,
+		Tone:           profile.Tone,
+		Language:       profile.Language,
+		SourceLanguage: profile.SourceLanguage,
+	}
+}
+
+// newLLM builds the language model client for an llm section of the
+// config. Its fallbacks are tried in order when the main endpoint fails.
+// defaultKey is used by OpenAI endpoints without an api_key.
+func newLLM(c config.LLM, defaultKey string) (llm.Client, error) {
+	clients := llm.Fallback{}
+	for _, e := range append([]config.LLM{c}, c.Fallbacks...) {
+		client, err := llmEndpoint(e, defaultKey)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+	return clients, nil
+}
+
+func llmEndpoint(e config.LLM, defaultKey string) (llm.Client, error) {
+	switch e.Provider {
+	case "", "openai", "openai-compatible":
+		key := e.APIKey
+		// Local servers usually need no key, so only OpenAI gets the default one.
+		if key == "" && e.Provider != "openai-compatible" {
+			key = defaultKey
+		}
+		return llm.OpenAI{
+			APIKey:         key,
+			BaseURL:        e.BaseURL,
+			Model:          e.Model,
+			Temperature:    e.Temperature,
+			MaxTokens:      e.MaxTokens,
+			Organization:   e.Organization,
+			ResponseFormat: e.ResponseFormat,
+		}, nil
+	case "anthropic":
+		return llm.Anthropic{APIKey: e.APIKey, BaseURL: e.BaseURL, Model: e.Model, Temperature: e.Temperature, MaxTokens: e.MaxTokens}, nil
+	case "gemini":
+		return llm.Gemini{APIKey: e.APIKey, BaseURL: e.BaseURL, Model: e.Model, Temperature: e.Temperature, MaxTokens: e.MaxTokens}, nil
+	}
+	return nil, fmt.Errorf("unknown llm provider %q", e.Provider)
+}
+
+// newVerifier returns nil when verification is disabled.
+func newVerifier(cfg config.Config) (Verifier, error) {
+	if !cfg.Verify.Enabled {
+		return nil, nil
+	}
+	client, err := newJudge(cfg, cfg.Verify.Model)
+	if err != nil {
+		return nil, err
+	}
+	return ChatGPTVerifier{LLM: client}, nil
+}
+
+// newJudge builds the language model of cfg, with model instead if it is
+// set, for checking rather than writing: proofreading and classifying
+// should be deterministic.
+func newJudge(cfg config.Config, model string) (llm.Client, error) {
+	c := cfg.LLM
+	if model != "" {
+		c.Model = model
+	}
+	temperature := 0.0
+	c.Temperature = &temperature
+	c.Fallbacks = nil
+	for _, f := range cfg.LLM.Fallbacks {
+		f.Temperature = &temperature
+		c.Fallbacks = append(c.Fallbacks, f)
+	}
+	return newLLM(c, cfg.ChatGPTAPIKey)
+}
+
+// captionOptions builds the caption options from the caption section of cfg.
+func captionOptions(cfg config.Config) caption.Options {
+	opts := caption.Options{
+		TemplateFile: cfg.Caption.TemplateFile,
+		MaxHashtags:  cfg.Caption.MaxHashtags,
+		MaxLength:    cfg.Caption.MaxLength,
+		CallToAction: cfg.Caption.CallToAction,
+	}
+	for _, p := range cfg.Caption.HashtagPools {
+		opts.Pools = append(opts.Pools, caption.Pool{Level: p.Level, Theme: p.Theme, Language: p.Language, Hashtags: p.Hashtags})
+	}
+	return opts
+}
+
+// newModerator returns nil when moderation is disabled.
+func newModerator(cfg config.Config) (Moderator, error) {
+	mod := ContentModerator{Blocklist: moderation.NewBlocklist(cfg.Moderation.BlockedWords)}
+	switch cfg.Moderation.Provider {
+	case "":
+		return nil, nil
+	case "openai":
+		mod.OpenAI = &moderation.OpenAI{APIKey: cfg.ChatGPTAPIKey, Model: cfg.Moderation.Model, BaseURL: cfg.Moderation.BaseURL}
+	case "local":
+	default:
+		return nil, fmt.Errorf("unknown moderation provider %q", cfg.Moderation.Provider)
+	}
+	if len(cfg.Moderation.BlockedTopics) > 0 {
+		client, err := newJudge(cfg, "")
+		if err != nil {
+			return nil, err
+		}
+		mod.Topics = &moderation.Topics{LLM: client, Topics: cfg.Moderation.BlockedTopics}
+	}
+	return mod, nil
+}
+
+// imageOptions returns the image settings of cfg with those of the profile applied.
+func imageOptions(cfg config.Config, profile config.Profile) leonardo.ImageOptions {
+	if profile.Image != nil {
+		return cfg.Image.Merge(*profile.Image)
+	}
+	return cfg.Image
+}
+
+// imageChecks requires generated images to have the requested size and aspect ratio.
+func imageChecks(opts leonardo.ImageOptions) imagecheck.Options {
+	width, height := opts.Width, opts.Height
+	if width == 0 {
+		width = leonardo.DefaultWidth
+	}
+	if height == 0 {
+		height = leonardo.DefaultHeight
+	}
+	return imagecheck.Options{MinWidth: width, MinHeight: height, AspectRatio: float64(width) / float64(height)}
+}
+
+func newImageGenerator(cfg config.Config, profile config.Profile, p *prompts.Templates) (ImageGenerator, error) {
+	switch cfg.Providers.Image {
+	case "", "leonardo":
+		opts := imageOptions(cfg, profile)
+		if err := opts.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid image options: %w", err)
+		}
+		return LeonardoImageGenerator{APIKey: cfg.LeonardoAPIKey, Options: opts, Prompts: p, Profile: profile}, nil
+	}
+	return nil, fmt.Errorf("unknown image provider %q", cfg.Providers.Image)
+}
+
+func newSpeechSynthesizer(cfg config.Config, profile config.Profile) (SpeechSynthesizer, error) {
+	switch cfg.Providers.Speech {
+	case "", "elevenlabs":
+		voiceID := cfg.ElevenLabsVoiceID
+		if v, ok := cfg.ElevenLabsVoices[language.LookupOr(profile.Language, language.DefaultTarget).Code]; ok {
+			voiceID = v
+		}
+		return ElevenLabsSynthesizer{APIKey: cfg.ElevenLabsAPIKey, VoiceID: voiceID}, nil
+	}
+	return nil, fmt.Errorf("unknown speech provider %q", cfg.Providers.Speech)
+}
+
+func newVideoRenderer(cfg config.Config) (VideoRenderer, error) {
+	switch cfg.Providers.Video {
+	case "", "ffmpeg":
+		return FFmpegRenderer{FontFile: cfg.VideoFontFile}, nil
+	}
+	return nil, fmt.Errorf("unknown video provider %q", cfg.Providers.Video)
+}
+
+func newMediaHost(cfg config.Config) (MediaHost, error) {
+	switch cfg.Providers.Host {
+	case "", "cloudinary":
+		return CloudinaryHost{URL: cfg.CloudinaryURL}, nil
+	}
+	return nil, fmt.Errorf("unknown media host %q", cfg.Providers.Host)
+}
+
+func newPublisher(cfg config.Config, profile config.Profile) (Publisher, error) {
+	switch cfg.Providers.Publisher {
+	case "", "instagram":
+		if profile.Account != "" {
+			account, ok := cfg.Accounts[profile.Account]
+			if !ok {
+				return nil, fmt.Errorf("unknown account %q", profile.Account)
+			}
+			return InstagramPublisher{UserID: account.InstagramUserID, AccessToken: account.InstagramAccessToken}, nil
+		}
+		return InstagramPublisher{UserID: cfg.InstagramUserID, AccessToken: cfg.InstagramAccessToken}, nil
+	}
+	return nil, fmt.Errorf("unknown publisher %q", cfg.Providers.Publisher)
+}
+
+// DeleteFileIfExists deletes the specified file if it exists.
+func DeleteFileIfExists(filename string) error {
+	// Check if the file exists.
+	if _, err := os.Stat(filename); err == nil {
+		// File exists, attempt deletion.
+		err = os.Remove(filename)
+		if err != nil {
+			return fmt.Errorf("failed to delete file: %v", err)
+		}
+		fmt.Printf("File %s deleted successfully.\n", filename)
+	} else if os.IsNotExist(err) {
+		// File does not exist.
+		fmt.Printf("File %s does not exist.\n", filename)
+	} else {
+		// Some other error occurred.
+		return fmt.Errorf("error checking file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"vokabelvision/chatgpt"
+)
+
+// Review states of a run that waits for human approval before publishing.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+	// ReviewFailed marks an approved run that failed to publish. It is not
+	// retried until it is approved again.
+	ReviewFailed = "publish_failed"
+)
+
+// regenerate lists the stages that are redone when an asset is regenerated.
+var regenerate = map[string][]string{
+	StageVocab: {StageVocab, StageImage, StageAudio, StageVideo},
+	StageImage: {StageImage, StageVideo},
+	StageAudio: {StageAudio, StageVideo},
+}
+
+// ListRuns loads the manifests of all runs in runsDir, oldest first.
+// Directories without a readable manifest are skipped.
+func ListRuns(runsDir string) ([]*Manifest, error) {
+	entries, err := os.ReadDir(runsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var runs []*Manifest
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		m, err := LoadManifest(runsDir, e.Name())
+		if err != nil {
+			continue
+		}
+		runs = append(runs, m)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID < runs[j].ID })
+	return runs, nil
+}
+
+// Queue returns the runs in runsDir that wait for review, including approved
+// runs that failed to publish, oldest first.
+func Queue(runsDir string) ([]*Manifest, error) {
+	runs, err := ListRuns(runsDir)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*Manifest
+	for _, m := range runs {
+		if m.Review == ReviewPending || m.Review == ReviewFailed {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+// Approve marks a rendered run as ready to be published at the next slot.
+func Approve(runsDir, id string) error {
+	m, err := LoadManifest(runsDir, id)
+	if err != nil {
+		return err
+	}
+	if m.Review != ReviewPending && m.Review != ReviewFailed {
+		return fmt.Errorf("run %s is not awaiting review (review status %q)", id, m.Review)
+	}
+	if !m.Done(StageVideo) {
+		return fmt.Errorf("run %s has no rendered video yet", id)
+	}
+	return m.setReview(ReviewApproved)
+}
+
+// Reject keeps a run from ever being published.
+func Reject(runsDir, id string) error {
+	m, err := LoadManifest(runsDir, id)
+	if err != nil {
+		return err
+	}
+	if m.Done(StagePublish) {
+		return fmt.Errorf("run %s has already been published", id)
+	}
+	return m.setReview(ReviewRejected)
+}
+
+// Regenerate redoes one asset of a queued run ("vocab", "image" or "audio")
+// together with the stages that depend on it, and puts the run back up for review.
+func (r *Runner) Regenerate(ctx context.Context, id, stage string) error {
+	stages, ok := regenerate[stage]
+	if !ok {
+		return fmt.Errorf("cannot regenerate %q", stage)
+	}
+	m, err := LoadManifest(r.RunsDir, id)
+	if err != nil {
+		return err
+	}
+	if m.Review != ReviewPending && m.Review != ReviewRejected && m.Review != ReviewFailed {
+		return fmt.Errorf("run %s is not in the review queue (review status %q)", id, m.Review)
+	}
+	for _, name := range stages {
+		m.Stage(name).Status = StatusPending
+	}
+	if err := m.setReview(ReviewPending); err != nil {
+		return err
+	}
+	fmt.Printf("Regenerating %s of run %s\n", stage, id)
+	if err := r.execute(ctx, m); err != nil {
+		r.recordFailure(m.ID, err)
+		return err
+	}
+	return nil
+}
+
+// ChooseImage makes the nth generated image of a queued run (counting from 1)
+// the one used, re-renders the video with it and puts the run back up for
+// review. Reviewers may choose an image that failed the checks.
+func (r *Runner) ChooseImage(ctx context.Context, id string, n int) error {
+	m, err := LoadManifest(r.RunsDir, id)
+	if err != nil {
+		return err
+	}
+	if m.Review != ReviewPending && m.Review != ReviewRejected && m.Review != ReviewFailed {
+		return fmt.Errorf("run %s is not in the review queue (review status %q)", id, m.Review)
+	}
+	if n < 1 || n > len(m.Images) {
+		return fmt.Errorf("run %s has %d images, not %d", id, len(m.Images), n)
+	}
+	for i := range m.Images {
+		m.Images[i].Chosen = i == n-1
+	}
+	m.ImagePath = m.Images[n-1].Path
+	m.Stage(StageVideo).Status = StatusPending
+	// The choice settles the image stage, even if it failed because no image
+	// passed the checks; running it again would replace the images.
+	if err := m.SetStatus(StageImage, StatusDone, nil); err != nil {
+		return err
+	}
+	if err := m.setReview(ReviewPending); err != nil {
+		return err
+	}
+	fmt.Printf("Re-rendering run %s with image %d\n", id, n)
+	if err := r.execute(ctx, m); err != nil {
+		r.recordFailure(m.ID, err)
+		return err
+	}
+	return nil
+}
+
+// PublishApproved publishes the oldest approved run for the runner's account
+// that has not been published yet. A run that fails to publish is marked
+// ReviewFailed, so it does not block the queue, and the next approved run
+// is tried instead. It does nothing if no run is approved.
+func (r *Runner) PublishApproved(ctx context.Context) error {
+	runs, err := ListRuns(r.RunsDir)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, m := range runs {
+		if m.Review != ReviewApproved || m.Done(StageCleanup) || m.Profile.Account != r.Profile.Account {
+			continue
+		}
+		fmt.Println("Publishing approved run", m.ID)
+		published := m.Done(StagePublish)
+		err := r.execute(ctx, m)
+		if err == nil {
+			if published {
+				// Only the stages after publishing were left; the slot is still free.
+				continue
+			}
+			return errors.Join(errs...)
+		}
+		r.recordFailure(m.ID, err)
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			return errors.Join(errs...)
+		}
+		// A reel that has been published only needs its remaining stages
+		// retried, which the next attempt does; anything else goes back to review.
+		if !m.Done(StagePublish) {
+			if err := m.setReview(ReviewFailed); err != nil {
+				log.Printf("Failed to save manifest for run %s: %v", m.ID, err)
+			}
+		}
+	}
+	if len(errs) == 0 {
+		fmt.Println("No approved reels to publish")
+	}
+	return errors.Join(errs...)
+}
+
+// queuedVocabs returns the vocab of m, if it has one, and the vocabs of the
+// other runs that wait for review or to be published.
+func (r *Runner) queuedVocabs(m *Manifest) ([]chatgpt.Vocab, error) {
+	var vocabs []chatgpt.Vocab
+	if m.Vocab.Word != "" {
+		vocabs = append(vocabs, m.Vocab)
+	}
+	runs, err := ListRuns(r.RunsDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, run := range runs {
+		if run.ID == m.ID || run.Vocab.Word == "" || run.Done(StagePublish) {
+			continue
+		}
+		switch run.Review {
+		case ReviewPending, ReviewApproved, ReviewFailed:
+			vocabs = append(vocabs, run.Vocab)
+		}
+	}
+	return vocabs, nil
+}
+
+func (m *Manifest) setReview(status string) error {
+	m.Review = status
+	m.ReviewedAt = time.Now()
+	return m.Save()
+}
@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/history"
+)
+
+// Weights of the parts of a candidate's score. They add up to 1.
+const (
+	weightNovelty     = 0.3
+	weightLevel       = 0.25
+	weightLength      = 0.15
+	weightDrawability = 0.3
+)
+
+// Sample sentences of this many words read best on a reel.
+const (
+	idealSentenceMin = 4
+	idealSentenceMax = 8
+)
+
+// Score rates a vocab candidate. Every part is between 0 and 1.
+type Score struct {
+	// Novelty is 0 for a posted word, 0.5 for a word related to a posted one and 1 otherwise.
+	Novelty float64 `json:"novelty"`
+	// Level is how close the candidate's estimated CEFR level is to the profile's.
+	Level float64 `json:"level"`
+	// Length is how close the sample sentence is to the ideal length.
+	Length float64 `json:"length"`
+	// Drawability is how easy the word is to show in a picture.
+	Drawability float64 `json:"drawability"`
+	Total       float64 `json:"total"`
+}
+
+// ScoredCandidate is a candidate with its score, as recorded in the manifest.
+type ScoredCandidate struct {
+	Word        string `json:"word"`
+	Translation string `json:"translation"`
+	Score       Score  `json:"score"`
+	Chosen      bool   `json:"chosen,omitempty"`
+	candidate   chatgpt.Candidate
+}
+
+// scoreCandidates scores candidates for a profile level against the posted
+// history and returns them best first, except that posted candidates come
+// after all others whatever their score. Candidates naming the same word as
+// a better one are dropped.
+func scoreCandidates(candidates []chatgpt.Candidate, level string, posted []history.Entry) []ScoredCandidate {
+	var scored []ScoredCandidate
+	for _, c := range candidates {
+		s := Score{
+			Novelty:     novelty(c.Vocab, posted),
+			Level:       levelScore(c.Level, level),
+			Length:      lengthScore(len(strings.Fields(c.Vocab.Sentence))),
+			Drawability: drawability(c),
+		}
+		s.Total = weightNovelty*s.Novelty + weightLevel*s.Level + weightLength*s.Length + weightDrawability*s.Drawability
+		scored = append(scored, ScoredCandidate{Word: c.Vocab.Word, Translation: c.Vocab.Translation, Score: s, candidate: c})
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		if fresh := scored[i].Score.Novelty > 0; fresh != (scored[j].Score.Novelty > 0) {
+			return fresh
+		}
+		return scored[i].Score.Total > scored[j].Score.Total
+	})
+	var unique []ScoredCandidate
+	for _, s := range scored {
+		dup := false
+		for _, u := range unique {
+			dup = dup || chatgpt.SameVocab(u.candidate.Vocab, s.candidate.Vocab)
+		}
+		if !dup {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+func novelty(v chatgpt.Vocab, posted []history.Entry) float64 {
+	score := 1.0
+	word, translation := chatgpt.Normalize(v.Word), chatgpt.Normalize(v.Translation)
+	for _, e := range posted {
+		if e.Vocab.Language() != v.Language() {
+			continue
+		}
+		if chatgpt.SameVocab(e.Vocab, v) {
+			return 0
+		}
+		if related(word, chatgpt.Normalize(e.Vocab.Word)) || related(translation, chatgpt.Normalize(e.Vocab.Translation)) {
+			score = 0.5
+		}
+	}
+	return score
+}
+
+// minRelated is the length in letters of the shortest word that makes
+// another related, so short words such as "ei" or "tea" do not.
+const minRelated = 4
+
+// related reports whether one normalized word starts or ends a word of the
+// other, such as "apfel" and "apfelbaum", "baum" and "apfelbaum", or
+// "apple" and "apple tree". The shorter word must have at least minRelated letters.
+func related(a, b string) bool {
+	if len([]rune(a)) > len([]rune(b)) {
+		a, b = b, a
+	}
+	if len([]rune(a)) < minRelated {
+		return false
+	}
+	if strings.HasPrefix(b, a) || strings.HasSuffix(b, a) {
+		return true
+	}
+	for _, w := range strings.Fields(b) {
+		if strings.HasPrefix(w, a) || strings.HasSuffix(w, a) {
+			return true
+		}
+	}
+	return false
+}
+
+var cefrLevels = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+func levelScore(estimate, target string) float64 {
+	if target == "" {
+		return 1
+	}
+	e, t := levelIndex(estimate), levelIndex(target)
+	if e < 0 || t < 0 {
+		return 0.5
+	}
+	return 1 - math.Abs(float64(e-t))/float64(len(cefrLevels)-1)
+}
+
+func levelIndex(level string) int {
+	for i, l := range cefrLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+func lengthScore(words int) float64 {
+	var off int
+	switch {
+	case words < idealSentenceMin:
+		off = idealSentenceMin - words
+	case words > idealSentenceMax:
+		off = words - idealSentenceMax
+	}
+	return math.Max(0, 1-0.25*float64(off))
+}
+
+// drawability uses the model's rating, or guesses from the part of speech
+// when there is none: concrete nouns are the easiest to picture.
+func drawability(c chatgpt.Candidate) float64 {
+	if c.Drawability >= 1 && c.Drawability <= 5 {
+		return float64(c.Drawability-1) / 4
+	}
+	switch strings.ToLower(c.Vocab.PartOfSpeech) {
+	case "noun":
+		return 0.75
+	case "verb", "adjective":
+		return 0.5
+	}
+	return 0.25
+}
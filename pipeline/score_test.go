@@ -0,0 +1,129 @@
+package pipeline
+
+import (
+	"testing"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/history"
+)
+
+func TestScoreCandidates(t *testing.T) {
+	de := chatgpt.VocabRequest{Language: "de"}
+	es := chatgpt.VocabRequest{Language: "es"}
+	candidate := func(word, translation, level string, drawability int, req chatgpt.VocabRequest) chatgpt.Candidate {
+		return chatgpt.Candidate{
+			Vocab:       chatgpt.Vocab{Word: word, Translation: translation, Sentence: "Das ist ein schönes Beispiel.", PartOfSpeech: "noun", Request: req},
+			Level:       level,
+			Drawability: drawability,
+		}
+	}
+	posted := []history.Entry{
+		{Vocab: chatgpt.Vocab{Word: "der Apfel", Translation: "apple", Request: de}},
+		{Vocab: chatgpt.Vocab{Word: "la casa", Translation: "house", Request: es}},
+	}
+	tests := []struct {
+		name       string
+		candidates []chatgpt.Candidate
+		level      string
+		// words are the expected candidates, best first.
+		words []string
+		// novelty is the expected novelty of each candidate in words.
+		novelty []float64
+	}{
+		{
+			name:       "posted words rank last",
+			candidates: []chatgpt.Candidate{candidate("der Apfel", "apple", "A1", 5, de), candidate("die Birne", "pear", "A1", 5, de)},
+			level:      "A1",
+			words:      []string{"die Birne", "der Apfel"},
+			novelty:    []float64{1, 0},
+		},
+		{
+			name:       "related words score half",
+			candidates: []chatgpt.Candidate{candidate("der Apfelbaum", "apple tree", "A1", 5, de)},
+			level:      "A1",
+			words:      []string{"der Apfelbaum"},
+			novelty:    []float64{0.5},
+		},
+		{
+			name:       "words posted in another language are new",
+			candidates: []chatgpt.Candidate{candidate("das Haus", "house", "A1", 5, de)},
+			level:      "A1",
+			words:      []string{"das Haus"},
+			novelty:    []float64{1},
+		},
+		{
+			name:       "closer level and easier picture rank higher",
+			candidates: []chatgpt.Candidate{candidate("die Freiheit", "freedom", "C1", 1, de), candidate("der Hund", "dog", "A1", 5, de), candidate("die Katze", "cat", "B2", 5, de)},
+			level:      "A1",
+			words:      []string{"der Hund", "die Katze", "die Freiheit"},
+			novelty:    []float64{1, 1, 1},
+		},
+		{
+			name:       "posted words rank last whatever their score",
+			candidates: []chatgpt.Candidate{candidate("der Apfel", "apple", "A1", 5, de), candidate("die Freiheit", "freedom", "C1", 1, de)},
+			level:      "A1",
+			words:      []string{"die Freiheit", "der Apfel"},
+			novelty:    []float64{1, 0},
+		},
+		{
+			name:       "short words are not related",
+			candidates: []chatgpt.Candidate{candidate("das Ei", "egg", "A1", 5, de), candidate("das Eis", "ice cream", "A1", 5, de)},
+			level:      "A1",
+			words:      []string{"das Ei", "das Eis"},
+			novelty:    []float64{1, 1},
+		},
+		{
+			name:       "duplicates keep the best",
+			candidates: []chatgpt.Candidate{candidate("die Straße", "road", "C2", 1, de), candidate("die Strasse", "street", "A1", 5, de)},
+			level:      "A1",
+			words:      []string{"die Strasse"},
+			novelty:    []float64{1},
+		},
+	}
+	for _, tt := range tests {
+		got := scoreCandidates(tt.candidates, tt.level, posted)
+		if len(got) != len(tt.words) {
+			t.Errorf("%s: got %d candidates, want %d", tt.name, len(got), len(tt.words))
+			continue
+		}
+		for i, s := range got {
+			if s.Word != tt.words[i] {
+				t.Errorf("%s: candidate %d is %q, want %q", tt.name, i, s.Word, tt.words[i])
+			}
+			if s.Score.Novelty != tt.novelty[i] {
+				t.Errorf("%s: novelty of %q is %v, want %v", tt.name, s.Word, s.Score.Novelty, tt.novelty[i])
+			}
+			if s.Score.Total < 0 || s.Score.Total > 1 {
+				t.Errorf("%s: total of %q is %v, not between 0 and 1", tt.name, s.Word, s.Score.Total)
+			}
+			if i > 0 && s.Score.Novelty > 0 && s.Score.Total > got[i-1].Score.Total {
+				t.Errorf("%s: %q scores higher than %q before it", tt.name, s.Word, got[i-1].Word)
+			}
+		}
+	}
+}
+
+func TestRelated(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"apfel", "apfelbaum", true},
+		{"baum", "apfelbaum", true},
+		{"apple", "apple tree", true},
+		{"apple tree", "tree", true},
+		{"apfelbaum", "apfel", true},
+		{"ei", "eis", false},
+		{"eis", "reise", false},
+		{"tea", "steak", false},
+		{"tea", "team", false},
+		{"haus", "rathaus", true},
+		{"rand", "brandung", false},
+		{"", "apfel", false},
+	}
+	for _, tt := range tests {
+		if got := related(tt.a, tt.b); got != tt.want {
+			t.Errorf("related(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package pipeline
+
+import (
+	"vokabelvision/caption"
+	"vokabelvision/chatgpt"
+)
+
+// overlayLines is the text drawn at the bottom of the reel.
+func overlayLines(v chatgpt.Vocab) []string {
+	var lines []string
+	if g := caption.GrammarLine(v); g != "" {
+		lines = append(lines, g)
+	}
+	if v.IPA != "" {
+		lines = append(lines, v.IPA)
+	}
+	if v.Sentence != "" {
+		lines = append(lines, v.Sentence)
+	}
+	if v.SentenceTranslation != "" {
+		lines = append(lines, v.SentenceTranslation)
+	}
+	return lines
+}
@@ -0,0 +1,133 @@
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"vokabelvision/chatgpt"
+	"vokabelvision/config"
+	"vokabelvision/language"
+)
+
+// The template files in a prompts directory.
+const (
+	// VocabFile renders the ChatGPT prompt that asks for a new vocab.
+	VocabFile = "vocab.tmpl"
+	// ImageFile renders the Leonardo.ai prompt for the image of a vocab.
+	ImageFile = "image.tmpl"
+)
+
+// defaults are used for template files missing from the prompts directory.
+//
+//go:embed vocab.tmpl image.tmpl
+var defaults embed.FS
+
+// Data is what the templates are executed with.
+type Data struct {
+	// Vocab is the vocab the image is made for. It is empty in VocabFile.
+	Vocab   chatgpt.Vocab
+	Profile config.Profile
+	// Language is the language taught and SourceLanguage the learners' language.
+	Language       language.Language
+	SourceLanguage language.Language
+	// Exclude lists recently posted words the vocab must not repeat.
+	Exclude []string
+	// Keys lists the JSON keys of the vocab answer, as "'a', 'b' and 'c'".
+	Keys string
+	// Grammar asks for the grammar fields that apply to Language.
+	Grammar string
+}
+
+// funcs are available in every template.
+var funcs = template.FuncMap{
+	"join":     strings.Join,
+	"or":       language.Or,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+	"audience": chatgpt.LevelAudience,
+}
+
+// Templates holds the parsed prompt templates.
+type Templates struct {
+	vocab *template.Template
+	image *template.Template
+}
+
+// Load parses the templates in dir, using the built-in template for any file
+// that does not exist, and checks that every template executes against a
+// sample vocab and profile.
+func Load(dir string) (*Templates, error) {
+	var t Templates
+	var err error
+	if t.vocab, err = parse(dir, VocabFile); err != nil {
+		return nil, err
+	}
+	if t.image, err = parse(dir, ImageFile); err != nil {
+		return nil, err
+	}
+	sample := config.Profile{Level: "A1", Theme: "food", PartOfSpeech: "noun", Tone: "playful", Language: "de", SourceLanguage: "en"}
+	if _, err := t.Vocab(sample, []string{"apple"}); err != nil {
+		return nil, err
+	}
+	vocab := chatgpt.Vocab{Word: "der Apfel", Translation: "apple", Sentence: "Der Apfel ist rot.", PartOfSpeech: "noun", Article: "der"}
+	if _, err := t.Image(sample, vocab); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parse reads the named template from dir, or the built-in one if dir has none.
+func parse(dir, name string) (*template.Template, error) {
+	text, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		text, err = defaults.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing prompt template: %v", err)
+	}
+	return t, nil
+}
+
+// Vocab renders the prompt that asks for a new vocab for profile.
+func (t *Templates) Vocab(profile config.Profile, exclude []string) (string, error) {
+	data := newData(profile)
+	data.Exclude = exclude
+	return execute(t.vocab, data)
+}
+
+// Image renders the image prompt for vocab.
+func (t *Templates) Image(profile config.Profile, vocab chatgpt.Vocab) (string, error) {
+	data := newData(profile)
+	data.Vocab = vocab
+	return execute(t.image, data)
+}
+
+func newData(profile config.Profile) Data {
+	req := chatgpt.VocabRequest{Language: profile.Language, SourceLanguage: profile.SourceLanguage}
+	return Data{
+		Profile:        profile,
+		Language:       language.LookupOr(profile.Language, language.DefaultTarget),
+		SourceLanguage: language.LookupOr(profile.SourceLanguage, language.DefaultSource),
+		Keys:           chatgpt.VocabKeys(),
+		Grammar:        chatgpt.GrammarInstructions(req),
+	}
+}
+
+func execute(t *template.Template, data Data) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("error rendering prompt template: %v", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"vokabelvision/config"
+	"vokabelvision/pipeline"
+)
+
+const queueUsage = "Usage: vokabelvision queue list | approve <id> | reject <id> | regenerate-vocab <id> | regenerate-image <id> | regenerate-audio <id> | choose-image <id> <n>"
+
+// QueueCommand handles the "queue" subcommands used to review generated reels.
+func QueueCommand(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return errors.New(queueUsage)
+	}
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if args[0] == "list" {
+		return listQueue(cfg.RunsDir)
+	}
+	if args[0] == "choose-image" && len(args) == 3 {
+		n, err := strconv.Atoi(args[2])
+		if err != nil {
+			return errors.New(queueUsage)
+		}
+		runner, err := loadRunnerForRun(args[1])
+		if err != nil {
+			return err
+		}
+		return runner.ChooseImage(ctx, args[1], n)
+	}
+	if len(args) != 2 {
+		return errors.New(queueUsage)
+	}
+	id := args[1]
+	switch args[0] {
+	case "approve":
+		if err := pipeline.Approve(cfg.RunsDir, id); err != nil {
+			return err
+		}
+		fmt.Printf("Run %s approved; it will be published at the next scheduled slot.\n", id)
+		return nil
+	case "reject":
+		if err := pipeline.Reject(cfg.RunsDir, id); err != nil {
+			return err
+		}
+		fmt.Printf("Run %s rejected.\n", id)
+		return nil
+	case "regenerate-vocab", "regenerate-image", "regenerate-audio":
+		runner, err := loadRunnerForRun(id)
+		if err != nil {
+			return err
+		}
+		return runner.Regenerate(ctx, id, strings.TrimPrefix(args[0], "regenerate-"))
+	}
+	return errors.New(queueUsage)
+}
+
+// listQueue prints the runs waiting for review.
+func listQueue(runsDir string) error {
+	pending, err := pipeline.Queue(runsDir)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		fmt.Println("The review queue is empty.")
+		return nil
+	}
+	for _, m := range pending {
+		fmt.Printf("%s  %s = %s\n", m.ID, m.Vocab.Word, m.Vocab.Translation)
+		if m.Review == pipeline.ReviewFailed {
+			for _, s := range m.Stages {
+				if s.Status == pipeline.StatusFailed {
+					fmt.Printf("    publishing failed after approval (%s): %s\n", s.Name, s.Error)
+				}
+			}
+		}
+		fmt.Printf("    sentence: %s\n", m.Vocab.Sentence)
+		fmt.Printf("    caption:  %s\n", m.Caption)
+		fmt.Printf("    video:    %s\n", m.VideoPath)
+		for i, img := range m.Images {
+			mark := " "
+			if img.Chosen {
+				mark = "*"
+			}
+			fmt.Printf("    %s image %d: %s (score %.2f)", mark, i+1, img.Path, img.Score)
+			if len(img.Problems) > 0 {
+				fmt.Printf(" failed: %s", strings.Join(img.Problems, "; "))
+			}
+			fmt.Println()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ErrBusy is returned by Trigger while another run is in progress.
+var ErrBusy = errors.New("a run is already in progress")
+
+// ErrUnknownEntry is returned by Trigger for an entry ID that is not scheduled.
+var ErrUnknownEntry = errors.New("unknown schedule entry")
+
+// Job is the work performed at each scheduled slot.
+type Job func(ctx context.Context) error
+
+// Entry describes one scheduled job.
+type Entry struct {
+	ID       int       `json:"id"`
+	Spec     string    `json:"spec"`
+	Timezone string    `json:"timezone"`
+	Jitter   string    `json:"jitter,omitempty"`
+	Next     time.Time `json:"next"`
+	Prev     time.Time `json:"prev,omitempty"`
+}
+
+// entry is what the scheduler remembers about a job besides its cron entry.
+type entry struct {
+	spec     string
+	timezone string
+	loc      *time.Location
+	jitter   time.Duration
+	schedule cron.Schedule
+	job      Job
+}
+
+// Scheduler runs jobs on cron schedules. Only one job runs at a time; a
+// slot that comes due while another job runs waits for it to finish.
+// Scheduled slots are skipped while the scheduler is paused.
+type Scheduler struct {
+	ctx     context.Context
+	cron    *cron.Cron
+	mu      sync.Mutex
+	entries map[cron.EntryID]entry
+	paused  atomic.Bool
+	// running holds a token while a job runs.
+	running chan struct{}
+	// triggered tracks runs started by Trigger, which cron does not know about.
+	triggered sync.WaitGroup
+}
+
+// New creates a scheduler. Jobs receive ctx, so cancelling it aborts a running job.
+func New(ctx context.Context) *Scheduler {
+	return &Scheduler{
+		ctx: ctx,
+		// A panicking job is logged and recovered so later slots still run.
+		cron:    cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
+		entries: map[cron.EntryID]entry{},
+		running: make(chan struct{}, 1),
+	}
+}
+
+// Add schedules job on the cron spec (minute hour day month day-of-week),
+// evaluated in the given IANA time zone. An empty timezone means local time.
+// Each run starts after a random delay of up to jitter.
+func (s *Scheduler) Add(spec, timezone string, jitter time.Duration, job Job) (int, error) {
+	loc := time.Local
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return 0, fmt.Errorf("invalid timezone %q: %v", timezone, err)
+		}
+	}
+	timezone = loc.String()
+	schedule, err := cron.ParseStandard("CRON_TZ=" + timezone + " " + spec)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cron spec %q: %v", spec, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := s.cron.Schedule(schedule, cron.FuncJob(func() {
+		if s.paused.Load() {
+			log.Printf("Scheduler is paused; skipping slot %q", spec)
+			return
+		}
+		if err := s.run(jitter, job); err != nil {
+			log.Printf("Scheduled run failed: %v", err)
+		}
+	}))
+	s.entries[id] = entry{spec: spec, timezone: timezone, loc: loc, jitter: jitter, schedule: schedule, job: job}
+	return int(id), nil
+}
+
+// Start starts the scheduler in its own goroutine.
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops scheduling new slots. The returned context is done once the
+// running job, if any, has returned.
+func (s *Scheduler) Stop() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cronDone := s.cron.Stop()
+	go func() {
+		<-cronDone.Done()
+		s.triggered.Wait()
+		cancel()
+	}()
+	return ctx
+}
+
+// Entries returns the scheduled jobs, ordered by their next run once the
+// scheduler has started.
+func (s *Scheduler) Entries() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var entries []Entry
+	for _, e := range s.cron.Entries() {
+		info := s.entries[e.ID]
+		next := e.Next
+		if next.IsZero() {
+			// The cron has not been started yet.
+			next = info.schedule.Next(time.Now())
+		}
+		out := Entry{ID: int(e.ID), Spec: info.spec, Timezone: info.timezone, Next: next.In(info.loc), Prev: e.Prev}
+		if info.jitter > 0 {
+			out.Jitter = info.jitter.String()
+		}
+		entries = append(entries, out)
+	}
+	return entries
+}
+
+// Pause skips scheduled slots until Resume is called. Triggered runs still run.
+func (s *Scheduler) Pause() {
+	s.paused.Store(true)
+}
+
+// Resume undoes Pause.
+func (s *Scheduler) Resume() {
+	s.paused.Store(false)
+}
+
+// Paused reports whether scheduled slots are being skipped.
+func (s *Scheduler) Paused() bool {
+	return s.paused.Load()
+}
+
+// Running reports whether a job is in progress.
+func (s *Scheduler) Running() bool {
+	return len(s.running) > 0
+}
+
+// Trigger starts the job of the given entry in the background, outside of its schedule.
+func (s *Scheduler) Trigger(id int) error {
+	s.mu.Lock()
+	e, ok := s.entries[cron.EntryID(id)]
+	s.mu.Unlock()
+	if !ok {
+		return ErrUnknownEntry
+	}
+	select {
+	case s.running <- struct{}{}:
+	default:
+		return ErrBusy
+	}
+	s.triggered.Add(1)
+	go func() {
+		defer s.triggered.Done()
+		defer func() { <-s.running }()
+		if err := e.job(s.ctx); err != nil {
+			log.Printf("Triggered run failed: %v", err)
+		}
+	}()
+	return nil
+}
+
+// run executes job after a random delay of up to jitter. If another job is
+// in progress by then, it waits for that job to finish.
+func (s *Scheduler) run(jitter time.Duration, job Job) error {
+	if jitter > 0 {
+		delay := time.Duration(rand.Int63n(int64(jitter)))
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case s.running <- struct{}{}:
+	}
+	defer func() { <-s.running }()
+	return job(s.ctx)
+}
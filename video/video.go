@@ -1,22 +1,92 @@
 package video
 
-import "os/exec"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrFFmpeg is returned when ffmpeg exits with an error.
+var ErrFFmpeg = errors.New("ffmpeg failed")
+
+// FFmpegError carries the output ffmpeg wrote to stderr before failing.
+type FFmpegError struct {
+	Err    error
+	Stderr string
+}
+
+func (e *FFmpegError) Error() string {
+	return ErrFFmpeg.Error() + ": " + e.Err.Error() + ": " + e.Stderr
+}
+
+func (e *FFmpegError) Unwrap() []error {
+	return []error{ErrFFmpeg, e.Err}
+}
+
+// Overlay is text drawn in a box at the bottom of the video.
+type Overlay struct {
+	Lines []string
+	// FontFile is the TrueType font to draw with. Empty uses ffmpeg's fontconfig default.
+	FontFile string
+}
+
+// GenerateVideo uses FFmpeg to create a video reel from the image and audio,
+// with the overlay text drawn on top if it has any lines.
+// The ffmpeg process is killed if ctx is cancelled.
+func GenerateVideo(ctx context.Context, imagePath, audioPath, outputVideoPath string, overlay Overlay) error {
+	filter := "scale=720:960" // For a 2:3 aspect ratio.
+	if len(overlay.Lines) > 0 {
+		// The text goes through a file so it needs no filtergraph escaping.
+		textPath := filepath.Join(filepath.Dir(outputVideoPath), "overlay.txt")
+		if err := os.WriteFile(textPath, []byte(strings.Join(overlay.Lines, "\n")), 0644); err != nil {
+			return err
+		}
+		filter += ",drawtext=expansion=none:textfile=" + filterPath(textPath)
+		if overlay.FontFile != "" {
+			filter += ":fontfile=" + filterPath(overlay.FontFile)
+		}
+		filter += ":fontsize=28:fontcolor=white:line_spacing=8:box=1:boxcolor=black@0.6:boxborderw=14" +
+			":x=(w-text_w)/2:y=h-text_h-60"
+	}
 
-// GenerateVideo uses FFmpeg to create a video reel from the image and audio.
-func GenerateVideo(imagePath, audioPath, outputVideoPath string) error {
 	// Example FFmpeg command: create a video using a static image and overlaying the audio.
 	// Adjust parameters as needed for looping audio or adding pauses.
-	cmd := exec.Command("ffmpeg",
+	cmd := exec.CommandContext(ctx, "ffmpeg",
 		"-loop", "1",
 		"-i", imagePath,
 		"-i", audioPath,
 		"-c:v", "libx264",
 		"-t", "10", // Duration of the video (seconds); adjust as needed.
 		"-pix_fmt", "yuv420p",
-		"-vf", "scale=720:960", // For a 2:3 aspect ratio.
+		"-vf", filter,
 		outputVideoPath,
 	)
+	var stderr bytes.Buffer
 	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &FFmpegError{Err: err, Stderr: lastLines(stderr.String(), 5)}
+	}
+	return nil
+}
+
+// filterPath escapes a file path for use as an option value in an ffmpeg filtergraph.
+func filterPath(path string) string {
+	return strings.NewReplacer(`\`, `/`, `:`, `\:`, `'`, `\'`, `,`, `\,`).Replace(filepath.ToSlash(path))
+}
+
+// lastLines returns at most n trailing lines of s; ffmpeg prints the actual error last.
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
 }